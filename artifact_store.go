@@ -0,0 +1,277 @@
+package neurvolve
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/couchbaselabs/logg"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const ARTIFACT_INDEX_FILENAME = "index.json"
+
+// Describes a saved cortex
+type Artifact struct {
+	Id         string  `json:"id"`
+	Uuid       string  `json:"uuid"`
+	Generation int     `json:"generation"`
+	Fitness    float64 `json:"fitness"`
+
+	// Where the cortex json and svg were written.  Empty for
+	// artifacts which are only held in memory.
+	JsonPath string `json:"json_path,omitempty"`
+	SvgPath  string `json:"svg_path,omitempty"`
+}
+
+// Artifact with its fitness as any json value, see MarshalJSON
+type jsonArtifact struct {
+	plainArtifact
+	Fitness interface{} `json:"fitness"`
+}
+
+// Artifact without its json methods
+type plainArtifact Artifact
+
+// Artifacts in the order they were saved
+type ArtifactIndex []Artifact
+
+// Somewhere to save cortexes to, for example the fittest cortex of
+// each generation.  Saving artifacts is opt-in: trainers and http
+// handlers only save when they have been given an ArtifactStore.
+type ArtifactStore interface {
+
+	// Save the cortex along with its generation and fitness
+	Save(evaldCortex EvaluatedCortex, generation int) (Artifact, error)
+
+	// Everything saved so far
+	Index() ArtifactIndex
+}
+
+// Saves each cortex as json and svg in a directory which is specific to
+// the run, and keeps an index.json of the artifacts next to them.
+type FileArtifactStore struct {
+	Dir string
+
+	mutex sync.Mutex
+	index ArtifactIndex
+}
+
+// Creates a directory for the run under baseDir, named after the run
+// and the current time so that separate runs never overwrite each other.
+func NewFileArtifactStore(baseDir, runName string) (*FileArtifactStore, error) {
+	if runName == "" {
+		runName = "run"
+	}
+	timestamp := time.Now().Format("20060102-150405")
+	dir := filepath.Join(baseDir, fmt.Sprintf("%v-%v", runName, timestamp))
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	return &FileArtifactStore{Dir: dir}, nil
+}
+
+func (store *FileArtifactStore) Save(evaldCortex EvaluatedCortex, generation int) (Artifact, error) {
+
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	artifact := newArtifact(evaldCortex, generation)
+	artifact.JsonPath = filepath.Join(store.Dir, artifact.Id+".json")
+	artifact.SvgPath = filepath.Join(store.Dir, artifact.Id+".svg")
+
+	jsonBytes, svgBytes, err := renderArtifact(evaldCortex)
+	if err != nil {
+		return Artifact{}, err
+	}
+	if err := ioutil.WriteFile(artifact.JsonPath, jsonBytes, 0644); err != nil {
+		return Artifact{}, err
+	}
+	if err := ioutil.WriteFile(artifact.SvgPath, svgBytes, 0644); err != nil {
+		return Artifact{}, err
+	}
+
+	index := append(store.index, artifact)
+	indexBytes, err := json.MarshalIndent(index, "", "  ")
+	if err != nil {
+		return Artifact{}, err
+	}
+	indexPath := filepath.Join(store.Dir, ARTIFACT_INDEX_FILENAME)
+	if err := ioutil.WriteFile(indexPath, indexBytes, 0644); err != nil {
+		return Artifact{}, err
+	}
+	store.index = index
+
+	return artifact, nil
+
+}
+
+func (store *FileArtifactStore) Index() ArtifactIndex {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	return append(ArtifactIndex{}, store.index...)
+}
+
+// Keeps artifacts in memory, mainly useful for tests
+type MemoryArtifactStore struct {
+	mutex sync.Mutex
+	index ArtifactIndex
+	json  map[string][]byte
+	svg   map[string][]byte
+}
+
+func NewMemoryArtifactStore() *MemoryArtifactStore {
+	return &MemoryArtifactStore{
+		json: make(map[string][]byte),
+		svg:  make(map[string][]byte),
+	}
+}
+
+func (store *MemoryArtifactStore) Save(evaldCortex EvaluatedCortex, generation int) (Artifact, error) {
+
+	jsonBytes, svgBytes, err := renderArtifact(evaldCortex)
+	if err != nil {
+		return Artifact{}, err
+	}
+
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	artifact := newArtifact(evaldCortex, generation)
+	store.index = append(store.index, artifact)
+	store.json[artifact.Id] = jsonBytes
+	store.svg[artifact.Id] = svgBytes
+	return artifact, nil
+
+}
+
+func (store *MemoryArtifactStore) Index() ArtifactIndex {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	return append(ArtifactIndex{}, store.index...)
+}
+
+// The cortex json of the artifact with the given id, or nil
+func (store *MemoryArtifactStore) Json(id string) []byte {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	return store.json[id]
+}
+
+// The cortex svg of the artifact with the given id, or nil
+func (store *MemoryArtifactStore) Svg(id string) []byte {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	return store.svg[id]
+}
+
+// Json has no numbers for infinity and NaN, so a non-finite fitness,
+// such as the +Inf of a cortex with zero error, is written as the
+// string "+Inf", "-Inf" or "NaN"
+func (artifact Artifact) MarshalJSON() ([]byte, error) {
+	var fitness interface{} = artifact.Fitness
+	if math.IsInf(artifact.Fitness, 0) || math.IsNaN(artifact.Fitness) {
+		fitness = strconv.FormatFloat(artifact.Fitness, 'g', -1, 64)
+	}
+	return json.Marshal(jsonArtifact{plainArtifact(artifact), fitness})
+}
+
+func (artifact *Artifact) UnmarshalJSON(data []byte) error {
+	decoded := jsonArtifact{}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return err
+	}
+	*artifact = Artifact(decoded.plainArtifact)
+	switch fitness := decoded.Fitness.(type) {
+	case float64:
+		artifact.Fitness = fitness
+	case string:
+		parsed, err := strconv.ParseFloat(fitness, 64)
+		if err != nil {
+			return fmt.Errorf("Invalid artifact fitness: %q", fitness)
+		}
+		artifact.Fitness = parsed
+	}
+	return nil
+}
+
+func (index ArtifactIndex) FindByUuid(uuid string) ArtifactIndex {
+	found := ArtifactIndex{}
+	for _, artifact := range index {
+		if artifact.Uuid == uuid {
+			found = append(found, artifact)
+		}
+	}
+	return found
+}
+
+func (index ArtifactIndex) FindByGeneration(generation int) ArtifactIndex {
+	found := ArtifactIndex{}
+	for _, artifact := range index {
+		if artifact.Generation == generation {
+			found = append(found, artifact)
+		}
+	}
+	return found
+}
+
+// A copy of the index sorted by descending fitness
+func (index ArtifactIndex) SortedByFitness() ArtifactIndex {
+	sorted := append(ArtifactIndex{}, index...)
+	sort.Stable(artifactsByFitness(sorted))
+	return sorted
+}
+
+// The fittest artifact, or false if the index is empty
+func (index ArtifactIndex) Fittest() (Artifact, bool) {
+	if len(index) == 0 {
+		return Artifact{}, false
+	}
+	return index.SortedByFitness()[0], true
+}
+
+type artifactsByFitness ArtifactIndex
+
+func (a artifactsByFitness) Len() int           { return len(a) }
+func (a artifactsByFitness) Swap(i, j int)      { a[i], a[j] = a[j], a[i] }
+func (a artifactsByFitness) Less(i, j int) bool { return a[i].Fitness > a[j].Fitness }
+
+// Save the cortex if there is a store, logging rather than failing
+// on errors so that a full disk doesn't abort training
+func saveArtifact(store ArtifactStore, evaldCortex EvaluatedCortex, generation int) {
+	if store == nil {
+		return
+	}
+	artifact, err := store.Save(evaldCortex, generation)
+	if err != nil {
+		logg.LogTo("NEURVOLVE", "Error saving cortex %v: %v", evaldCortex.Cortex.NodeId.UUID, err)
+		return
+	}
+	logg.LogTo("NEURVOLVE", "Saved artifact %v fitness: %v", artifact.Id, artifact.Fitness)
+}
+
+func newArtifact(evaldCortex EvaluatedCortex, generation int) Artifact {
+	uuid := evaldCortex.Cortex.NodeId.UUID
+	return Artifact{
+		Id:         fmt.Sprintf("%v-gen%d", uuid, generation),
+		Uuid:       uuid,
+		Generation: generation,
+		Fitness:    evaldCortex.Fitness,
+	}
+}
+
+func renderArtifact(evaldCortex EvaluatedCortex) (jsonBytes, svgBytes []byte, err error) {
+	jsonBytes, err = json.MarshalIndent(evaldCortex.Cortex, "", "    ")
+	if err != nil {
+		return nil, nil, err
+	}
+	buffer := &bytes.Buffer{}
+	evaldCortex.Cortex.RenderSVG(buffer)
+	return jsonBytes, buffer.Bytes(), nil
+}
@@ -0,0 +1,120 @@
+package neurvolve
+
+import (
+	"encoding/json"
+	"github.com/couchbaselabs/go.assert"
+	ng "github.com/maxxk/neurgo"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestMemoryArtifactStore(t *testing.T) {
+
+	store := NewMemoryArtifactStore()
+
+	cortex := &ng.Cortex{NodeId: ng.NewCortexId("cortex-1")}
+	otherCortex := &ng.Cortex{NodeId: ng.NewCortexId("cortex-2")}
+
+	_, err := store.Save(EvaluatedCortex{Cortex: cortex, Fitness: 0.5}, 0)
+	assert.True(t, err == nil)
+	_, err = store.Save(EvaluatedCortex{Cortex: otherCortex, Fitness: 0.9}, 0)
+	assert.True(t, err == nil)
+	artifact, err := store.Save(EvaluatedCortex{Cortex: cortex, Fitness: 0.7}, 1)
+	assert.True(t, err == nil)
+
+	assert.Equals(t, artifact.Uuid, cortex.NodeId.UUID)
+	assert.Equals(t, artifact.Generation, 1)
+	assert.Equals(t, artifact.Fitness, 0.7)
+	assert.True(t, len(store.Json(artifact.Id)) > 0)
+
+	index := store.Index()
+	assert.Equals(t, len(index), 3)
+	assert.Equals(t, len(index.FindByUuid(cortex.NodeId.UUID)), 2)
+	assert.Equals(t, len(index.FindByGeneration(0)), 2)
+
+	fittest, ok := index.Fittest()
+	assert.True(t, ok)
+	assert.Equals(t, fittest.Uuid, otherCortex.NodeId.UUID)
+
+	_, ok = ArtifactIndex{}.Fittest()
+	assert.False(t, ok)
+
+}
+
+func TestFileArtifactStore(t *testing.T) {
+
+	baseDir, err := ioutil.TempDir("", "neurvolve-artifacts")
+	assert.True(t, err == nil)
+	defer os.RemoveAll(baseDir)
+
+	store, err := NewFileArtifactStore(baseDir, "xnor")
+	assert.True(t, err == nil)
+	assert.Equals(t, filepath.Dir(store.Dir), baseDir)
+
+	cortex := &ng.Cortex{NodeId: ng.NewCortexId("cortex-1")}
+	artifact, err := store.Save(EvaluatedCortex{Cortex: cortex, Fitness: 0.5}, 3)
+	assert.True(t, err == nil)
+
+	_, err = os.Stat(artifact.JsonPath)
+	assert.True(t, err == nil)
+	_, err = os.Stat(artifact.SvgPath)
+	assert.True(t, err == nil)
+	_, err = os.Stat(filepath.Join(store.Dir, ARTIFACT_INDEX_FILENAME))
+	assert.True(t, err == nil)
+
+	assert.Equals(t, len(store.Index()), 1)
+
+}
+
+func TestFileArtifactStoreInfiniteFitness(t *testing.T) {
+
+	baseDir, err := ioutil.TempDir("", "neurvolve-artifacts")
+	assert.True(t, err == nil)
+	defer os.RemoveAll(baseDir)
+
+	store, err := NewFileArtifactStore(baseDir, "xnor")
+	assert.True(t, err == nil)
+
+	// a cortex with zero error
+	cortex := &ng.Cortex{NodeId: ng.NewCortexId("cortex-1")}
+	_, err = store.Save(EvaluatedCortex{Cortex: cortex, Fitness: math.Inf(1)}, 0)
+	assert.True(t, err == nil)
+	assert.Equals(t, len(store.Index()), 1)
+
+	indexBytes, err := ioutil.ReadFile(filepath.Join(store.Dir, ARTIFACT_INDEX_FILENAME))
+	assert.True(t, err == nil)
+	index := ArtifactIndex{}
+	assert.True(t, json.Unmarshal(indexBytes, &index) == nil)
+	assert.Equals(t, len(index), 1)
+	assert.True(t, math.IsInf(index[0].Fitness, 1))
+	assert.Equals(t, index[0].Uuid, cortex.NodeId.UUID)
+
+	// finite fitness is still written as a number
+	jsonBytes, err := json.Marshal(Artifact{Fitness: 0.5})
+	assert.True(t, err == nil)
+	assert.True(t, strings.Contains(string(jsonBytes), `"fitness":0.5`))
+
+}
+
+func TestPopulationTrainerSavesFittest(t *testing.T) {
+
+	store := NewMemoryArtifactStore()
+	pt := &PopulationTrainer{
+		FitnessThreshold: 2.0,
+		MaxGenerations:   2,
+		CortexMutator:    NoOpMutator,
+		Artifacts:        store,
+	}
+
+	population := []*ng.Cortex{SingleNeuronCortex("cortex1"), SingleNeuronCortex("cortex2")}
+	pt.Train(population, ConstantScape{fitness: 1.0}, NewNullRecorder())
+
+	index := store.Index()
+	assert.Equals(t, len(index), 2)
+	assert.Equals(t, index[1].Generation, 1)
+
+}
@@ -0,0 +1,256 @@
+// Runs trainers on a standard set of tasks over several seeds, and
+// reports how often and how quickly they solve them.
+//
+// Run from the command line with:
+//
+//	$ go run cmd/neurvolve/main.go benchmark -tasks xor,parity3 -seeds 5
+//
+// or as go benchmarks, which report the same measures per trainer:
+//
+//	$ go test -run NONE -bench . ./benchmark
+package benchmark
+
+import (
+	"fmt"
+	nv "github.com/maxxk/neurvolve"
+	"io"
+	"math"
+	"math/rand"
+	"text/tabwriter"
+	"time"
+)
+
+// Makes trainers which stop once the task's fitness threshold is reached
+// or the budget is exhausted
+type Trainer struct {
+	Name       string
+	NewTrainer func(task Task, budget *nv.EvaluationBudget) nv.Trainer
+}
+
+// The outcome of training on a task with one seed
+type Result struct {
+	Task    string
+	Trainer string
+	Seed    int64
+
+	// Whether the fittest cortex reaches the task's fitness threshold when
+	// rescored on a fresh scape, and its fitness there
+	Succeeded bool
+	Fitness   float64
+
+	// Whether the trainer itself reported reaching the threshold, on the
+	// scape it trained on
+	TrainerSucceeded bool
+
+	// Number of calls to Fitness and FitnessAgainst of the scape
+	Evaluations int
+
+	// Whether the trainer stopped because it used up its budget
+	BudgetExhausted bool
+	StopReason      nv.StopReason
+
+	// Size of the fittest cortex
+	Neurons     int
+	Connections int
+
+	Duration time.Duration
+}
+
+// Results of a trainer on a task, over all seeds
+type Summary struct {
+	Task    string
+	Trainer string
+
+	Runs        int
+	SuccessRate float64
+
+	// Mean over the successful runs, or NaN if there were none
+	EvaluationsToSolve float64
+
+	// Means over all runs
+	Neurons     float64
+	Connections float64
+	Duration    time.Duration
+}
+
+// The population, stochastic hill climber and topology mutating trainers,
+// with budgets which solve the easier tasks in seconds
+func StandardTrainers() []Trainer {
+	return []Trainer{
+		PopulationTrainer(30, 200),
+		StochasticHillClimber(200, 20),
+		TopologyMutatingTrainer(10, 5, 100),
+	}
+}
+
+// Find the standard trainer with the given name
+func FindTrainer(name string) (Trainer, bool) {
+	for _, trainer := range StandardTrainers() {
+		if trainer.Name == name {
+			return trainer, true
+		}
+	}
+	return Trainer{}, false
+}
+
+// A population trainer with a population of new cortexes from the task,
+// mutated with the non topological mutators
+func PopulationTrainer(populationSize, maxGenerations int) Trainer {
+	return Trainer{
+		Name: "population",
+		NewTrainer: func(task Task, budget *nv.EvaluationBudget) nv.Trainer {
+			return &nv.PopulationTrainer{
+				FitnessThreshold: task.FitnessThreshold,
+				MaxGenerations:   maxGenerations,
+				CortexMutator:    nv.CombinedCortexMutator(nv.CortexMutatorsNonTopological()...),
+				Budget:           budget,
+				PopulationSize:   populationSize,
+			}
+		},
+	}
+}
+
+// A stochastic hill climber, which keeps the topology of the task's cortex
+func StochasticHillClimber(maxIterationsBeforeRestart, maxAttempts int) Trainer {
+	return Trainer{
+		Name: "stochastic_hill_climber",
+		NewTrainer: func(task Task, budget *nv.EvaluationBudget) nv.Trainer {
+			shc := stochasticHillClimber(task, maxIterationsBeforeRestart, maxAttempts)
+			shc.Budget = budget
+			return shc
+		},
+	}
+}
+
+// A topology mutating trainer, which hill climbs each new topology
+// for up to maxHillClimbingIterations
+func TopologyMutatingTrainer(maxIterationsBeforeRestart, maxAttempts, maxHillClimbingIterations int) Trainer {
+	return Trainer{
+		Name: "topology_mutating",
+		NewTrainer: func(task Task, budget *nv.EvaluationBudget) nv.Trainer {
+			return &nv.TopologyMutatingTrainer{
+				MaxIterationsBeforeRestart: maxIterationsBeforeRestart,
+				MaxAttempts:                maxAttempts,
+				StochasticHillClimber:      stochasticHillClimber(task, maxHillClimbingIterations, 1),
+				Budget:                     budget,
+				DisableReseeding:           true,
+			}
+		},
+	}
+}
+
+func stochasticHillClimber(task Task, maxIterationsBeforeRestart, maxAttempts int) *nv.StochasticHillClimber {
+	return &nv.StochasticHillClimber{
+		FitnessThreshold:           task.FitnessThreshold,
+		MaxIterationsBeforeRestart: maxIterationsBeforeRestart,
+		MaxAttempts:                maxAttempts,
+		WeightSaturationRange:      []float64{-10 * math.Pi, 10 * math.Pi},
+		DisableReseeding:           true,
+	}
+}
+
+// Run every trainer on every task with the seeds 1 to numSeeds, giving
+// each run at most maxEvaluations, or no limit if 0
+func Run(tasks []Task, trainers []Trainer, numSeeds, maxEvaluations int) []Result {
+	results := make([]Result, 0)
+	for _, task := range tasks {
+		for _, trainer := range trainers {
+			for seed := int64(1); seed <= int64(numSeeds); seed++ {
+				results = append(results, RunOnce(task, trainer, seed, maxEvaluations))
+			}
+		}
+	}
+	return results
+}
+
+// Train on the task with math/rand seeded with the seed
+func RunOnce(task Task, trainer Trainer, seed int64, maxEvaluations int) Result {
+
+	rand.Seed(seed)
+	budget := &nv.EvaluationBudget{MaxEvaluations: maxEvaluations}
+
+	start := time.Now()
+	trainResult := trainer.NewTrainer(task, budget).TrainFrom(task.NewCortex, task.NewScape())
+	duration := time.Since(start)
+	fittest := trainResult.Cortex
+
+	// scored on a fresh scape, so it doesn't count as an evaluation
+	fitness := task.NewScape().Fitness(fittest)
+
+	return Result{
+		Task:             task.Name,
+		Trainer:          trainer.Name,
+		Seed:             seed,
+		Succeeded:        fitness >= task.FitnessThreshold,
+		Fitness:          fitness,
+		TrainerSucceeded: trainResult.Succeeded,
+		Evaluations:      trainResult.Evaluations,
+		BudgetExhausted:  budget.Exhausted(),
+		StopReason:       trainResult.StopReason,
+		Neurons:          len(fittest.Neurons),
+		Connections:      nv.NumConnections(fittest),
+		Duration:         duration,
+	}
+
+}
+
+// Summarize the results of each trainer on each task, in the order in
+// which they first appear
+func Summarize(results []Result) []Summary {
+
+	summaries := make([]Summary, 0)
+	index := make(map[[2]string]int)
+	successfulEvaluations := make([]int, 0)
+
+	for _, result := range results {
+		key := [2]string{result.Task, result.Trainer}
+		i, ok := index[key]
+		if !ok {
+			i = len(summaries)
+			index[key] = i
+			summaries = append(summaries, Summary{Task: result.Task, Trainer: result.Trainer})
+			successfulEvaluations = append(successfulEvaluations, 0)
+		}
+		summary := &summaries[i]
+		summary.Runs += 1
+		if result.Succeeded {
+			summary.SuccessRate += 1
+			successfulEvaluations[i] += result.Evaluations
+		}
+		summary.Neurons += float64(result.Neurons)
+		summary.Connections += float64(result.Connections)
+		summary.Duration += result.Duration
+	}
+
+	for i := range summaries {
+		summary := &summaries[i]
+		numSuccesses := summary.SuccessRate
+		summary.EvaluationsToSolve = math.NaN()
+		if numSuccesses > 0 {
+			summary.EvaluationsToSolve = float64(successfulEvaluations[i]) / numSuccesses
+		}
+		runs := float64(summary.Runs)
+		summary.SuccessRate /= runs
+		summary.Neurons /= runs
+		summary.Connections /= runs
+		summary.Duration /= time.Duration(summary.Runs)
+	}
+	return summaries
+
+}
+
+// Write the summaries as a table with aligned columns
+func WriteTable(w io.Writer, summaries []Summary) error {
+	table := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintf(table, "task\ttrainer\truns\tsuccess rate\tevaluations to solve\tneurons\tconnections\twall time\n")
+	for _, summary := range summaries {
+		evaluations := "-"
+		if !math.IsNaN(summary.EvaluationsToSolve) {
+			evaluations = fmt.Sprintf("%.0f", summary.EvaluationsToSolve)
+		}
+		fmt.Fprintf(table, "%v\t%v\t%d\t%.2f\t%v\t%.1f\t%.1f\t%v\n",
+			summary.Task, summary.Trainer, summary.Runs, summary.SuccessRate, evaluations,
+			summary.Neurons, summary.Connections, summary.Duration)
+	}
+	return table.Flush()
+}
@@ -0,0 +1,181 @@
+package benchmark
+
+import (
+	"bytes"
+	"github.com/couchbaselabs/go.assert"
+	"github.com/couchbaselabs/logg"
+	nv "github.com/maxxk/neurvolve"
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+func init() {
+	logg.LogKeys["MAIN"] = false
+	logg.LogKeys["NEURVOLVE"] = false
+}
+
+func TestStandardTasks(t *testing.T) {
+
+	names := make(map[string]bool)
+	for _, task := range StandardTasks() {
+		assert.False(t, names[task.Name])
+		names[task.Name] = true
+
+		cortex := task.NewCortex()
+		fitness := task.NewScape().Fitness(cortex)
+		assert.False(t, math.IsNaN(fitness))
+	}
+
+	task, ok := FindTask("parity3")
+	assert.True(t, ok)
+	samples := task.NewScape().(*nv.TrainingSampleScape).Examples()
+	assert.Equals(t, len(samples), 8)
+	_, ok = FindTask("nonexistent")
+	assert.False(t, ok)
+
+}
+
+func TestRunOnce(t *testing.T) {
+
+	trainer := Trainer{
+		Name: "lazy",
+		NewTrainer: func(task Task, budget *nv.EvaluationBudget) nv.Trainer {
+			return lazyTrainer{budget}
+		},
+	}
+
+	task := XorTask()
+	task.FitnessThreshold = 0
+	result := RunOnce(task, trainer, 3, 0)
+	assert.Equals(t, result.Task, "xor")
+	assert.Equals(t, result.Trainer, "lazy")
+	assert.Equals(t, result.Seed, int64(3))
+	assert.True(t, result.Succeeded)
+	assert.False(t, result.TrainerSucceeded)
+	assert.Equals(t, result.Evaluations, 2)
+	assert.False(t, result.BudgetExhausted)
+	assert.Equals(t, result.StopReason, nv.STOP_MAX_ITERATIONS)
+	assert.Equals(t, result.Neurons, 3)
+	assert.Equals(t, result.Connections, 4)
+
+	task.FitnessThreshold = math.Inf(1)
+	assert.False(t, RunOnce(task, trainer, 3, 0).Succeeded)
+	assert.True(t, RunOnce(task, trainer, 3, 2).BudgetExhausted)
+
+}
+
+// Returns the starting cortex after two evaluations
+type lazyTrainer struct {
+	budget *nv.EvaluationBudget
+}
+
+func (trainer lazyTrainer) TrainFrom(newCortex nv.CortexFactory, scape nv.Scape) nv.TrainResult {
+	scape = trainer.budget.Scape(scape)
+	cortex := newCortex()
+	scape.Fitness(cortex)
+	fitness := scape.Fitness(cortex)
+	return nv.TrainResult{
+		Cortex:      cortex,
+		Fitness:     fitness,
+		StopReason:  nv.STOP_MAX_ITERATIONS,
+		Evaluations: 2,
+	}
+}
+
+func TestPopulationTrainerRuns(t *testing.T) {
+	results := Run([]Task{XorTask()}, []Trainer{PopulationTrainer(4, 2)}, 2, 0)
+	assert.Equals(t, len(results), 2)
+	for _, result := range results {
+		assert.True(t, result.Evaluations >= 4)
+	}
+}
+
+func TestMaxEvaluations(t *testing.T) {
+	task := XorTask()
+	task.FitnessThreshold = math.Inf(1)
+	for _, trainer := range []Trainer{PopulationTrainer(10, 100), StochasticHillClimber(20, 10)} {
+		result := RunOnce(task, trainer, 1, 50)
+		assert.True(t, result.BudgetExhausted)
+		assert.Equals(t, result.StopReason, nv.STOP_BUDGET_EXHAUSTED)
+		assert.True(t, result.Evaluations >= 50)
+		assert.True(t, result.Evaluations <= 60)
+	}
+}
+
+func TestSummarize(t *testing.T) {
+
+	results := []Result{
+		{Task: "xor", Trainer: "a", Succeeded: true, Evaluations: 10, Neurons: 3, Duration: time.Second},
+		{Task: "xor", Trainer: "a", Succeeded: false, Evaluations: 100, Neurons: 5, Duration: 3 * time.Second},
+		{Task: "xor", Trainer: "b", Succeeded: false, Evaluations: 100, Neurons: 3},
+		{Task: "xor", Trainer: "a", Succeeded: true, Evaluations: 20, Neurons: 4, Duration: 2 * time.Second},
+	}
+	summaries := Summarize(results)
+	assert.Equals(t, len(summaries), 2)
+
+	summary := summaries[0]
+	assert.Equals(t, summary.Trainer, "a")
+	assert.Equals(t, summary.Runs, 3)
+	assert.Equals(t, summary.SuccessRate, 2.0/3)
+	assert.Equals(t, summary.EvaluationsToSolve, 15.0)
+	assert.Equals(t, summary.Neurons, 4.0)
+	assert.Equals(t, summary.Duration, 2*time.Second)
+
+	assert.Equals(t, summaries[1].SuccessRate, 0.0)
+	assert.True(t, math.IsNaN(summaries[1].EvaluationsToSolve))
+
+	buffer := &bytes.Buffer{}
+	assert.True(t, WriteTable(buffer, summaries) == nil)
+	lines := strings.Split(strings.TrimSpace(buffer.String()), "\n")
+	assert.Equals(t, len(lines), 3)
+	assert.True(t, strings.HasPrefix(lines[0], "task"))
+	assert.True(t, strings.Contains(lines[1], "0.67"))
+	assert.True(t, strings.Contains(lines[2], " - "))
+
+}
+
+// Each iteration trains with the next seed, and the measures of the
+// task table are reported per trainer
+func benchmarkTask(b *testing.B, task Task) {
+	for _, trainer := range StandardTrainers() {
+		b.Run(trainer.Name, func(b *testing.B) {
+			results := make([]Result, 0)
+			for i := 0; i < b.N; i++ {
+				results = append(results, RunOnce(task, trainer, int64(i+1), 0))
+			}
+			summary := Summarize(results)[0]
+			b.ReportMetric(summary.SuccessRate, "success-rate")
+			if !math.IsNaN(summary.EvaluationsToSolve) {
+				b.ReportMetric(summary.EvaluationsToSolve, "evals-to-solve")
+			}
+			b.ReportMetric(summary.Neurons, "neurons")
+			b.ReportMetric(summary.Connections, "connections")
+		})
+	}
+}
+
+func BenchmarkXor(b *testing.B) {
+	benchmarkTask(b, XorTask())
+}
+
+func BenchmarkParity3(b *testing.B) {
+	benchmarkTask(b, ParityTask(3))
+}
+
+func BenchmarkSine(b *testing.B) {
+	benchmarkTask(b, SineRegressionTask())
+}
+
+func BenchmarkTwoSpirals(b *testing.B) {
+	benchmarkTask(b, TwoSpiralsTask())
+}
+
+func BenchmarkIris(b *testing.B) {
+	benchmarkTask(b, IrisTask())
+}
+
+func BenchmarkPoleBalancing(b *testing.B) {
+	benchmarkTask(b, PoleBalancingTask())
+}
@@ -0,0 +1,172 @@
+package benchmark
+
+import (
+	"fmt"
+	ng "github.com/maxxk/neurgo"
+	nv "github.com/maxxk/neurvolve"
+	"math"
+	"math/rand"
+)
+
+// Seed of the generator for the built-in datasets, so that every run
+// of a task sees the same samples
+const DATASET_SEED = 1
+
+// A problem to train cortexes on.  A run succeeds if the fittest cortex
+// reaches the FitnessThreshold on a fresh scape.
+type Task struct {
+	Name string
+
+	// A new scape for each run, so that runs don't share any state
+	NewScape func() nv.Scape
+
+	// A new starting cortex, with random weights
+	NewCortex func() *ng.Cortex
+
+	FitnessThreshold float64
+}
+
+// XOR, 3-bit parity, sine regression, two spirals, Iris-like
+// classification and pole balancing
+func StandardTasks() []Task {
+	return []Task{
+		XorTask(),
+		ParityTask(3),
+		SineRegressionTask(),
+		TwoSpiralsTask(),
+		IrisTask(),
+		PoleBalancingTask(),
+	}
+}
+
+// Find the standard task with the given name
+func FindTask(name string) (Task, bool) {
+	for _, task := range StandardTasks() {
+		if task.Name == name {
+			return task, true
+		}
+	}
+	return Task{}, false
+}
+
+func XorTask() Task {
+	samples := make([]*ng.TrainingSample, 0)
+	for _, a := range []float64{0, 1} {
+		for _, b := range []float64{0, 1} {
+			samples = append(samples, sample([]float64{a, b}, []float64{math.Abs(a - b)}))
+		}
+	}
+	return supervisedTask("xor", samples, 2, 100)
+}
+
+// The parity of numBits bits: 1 if an odd number of them are set
+func ParityTask(numBits int) Task {
+	samples := make([]*ng.TrainingSample, 0)
+	for i := 0; i < 1<<uint(numBits); i++ {
+		inputs := make([]float64, numBits)
+		parity := 0.0
+		for bit := range inputs {
+			if i&(1<<uint(bit)) != 0 {
+				inputs[bit] = 1
+				parity = 1 - parity
+			}
+		}
+		samples = append(samples, sample(inputs, []float64{parity}))
+	}
+	return supervisedTask(fmt.Sprintf("parity%d", numBits), samples, numBits, 100)
+}
+
+// Approximate 0.5 + 0.5 sin(x) at 20 points in [-pi, pi], with x scaled
+// to [-1, 1]
+func SineRegressionTask() Task {
+	samples := make([]*ng.TrainingSample, 0)
+	numPoints := 20
+	for i := 0; i < numPoints; i++ {
+		x := -math.Pi + 2*math.Pi*float64(i)/float64(numPoints-1)
+		samples = append(samples, sample([]float64{x / math.Pi}, []float64{0.5 + 0.5*math.Sin(x)}))
+	}
+	return supervisedTask("sine", samples, 4, 200)
+}
+
+// Tell apart the points of two interleaved spirals, with 24 points per
+// spiral.  This is a much harder problem than the others.
+func TwoSpiralsTask() Task {
+	samples := make([]*ng.TrainingSample, 0)
+	numPoints := 24
+	for i := 0; i < numPoints; i++ {
+		angle := float64(i) * math.Pi / 8
+		radius := 0.1 + 0.9*float64(i)/float64(numPoints)
+		x, y := radius*math.Cos(angle), radius*math.Sin(angle)
+		samples = append(samples, sample([]float64{x, y}, []float64{1}))
+		samples = append(samples, sample([]float64{-x, -y}, []float64{0}))
+	}
+	return supervisedTask("two_spirals", samples, 8, 10)
+}
+
+// Three classes of 30 samples with 4 features each, drawn from gaussians
+// around the means of the classes of the Iris dataset, with one-hot
+// encoded classes
+func IrisTask() Task {
+
+	means := [][]float64{
+		{5.0, 3.4, 1.5, 0.2},
+		{5.9, 2.8, 4.3, 1.3},
+		{6.6, 3.0, 5.6, 2.0},
+	}
+	deviations := []float64{0.4, 0.3, 0.5, 0.2}
+
+	random := rand.New(rand.NewSource(DATASET_SEED))
+	samples := make([]*ng.TrainingSample, 0)
+	for class, mean := range means {
+		for i := 0; i < 30; i++ {
+			inputs := make([]float64, len(mean))
+			for j := range inputs {
+				// scale the features to roughly [-1, 1]
+				inputs[j] = (mean[j]+random.NormFloat64()*deviations[j])/4 - 1
+			}
+			outputs := make([]float64, len(means))
+			outputs[class] = 1
+			samples = append(samples, sample(inputs, outputs))
+		}
+	}
+	return supervisedTask("iris", samples, 4, 10)
+
+}
+
+// Balance the pole for all steps of a nv.PoleBalancingScape
+func PoleBalancingTask() Task {
+	return Task{
+		Name: "pole_balancing",
+		NewScape: func() nv.Scape {
+			return nv.NewPoleBalancingScape()
+		},
+		NewCortex:        nv.PoleBalancingCortex,
+		FitnessThreshold: 0.999,
+	}
+}
+
+// A task scored by nv.TrainingSampleScape, starting from a cortex with a
+// hidden layer of the given width
+func supervisedTask(name string, samples []*ng.TrainingSample, hiddenLayerWidth int, fitnessThreshold float64) Task {
+	factory := &nv.PopulationFactory{
+		Sensors:          []nv.SensorSpec{{Name: "sensor", VectorLength: len(samples[0].SampleInputs[0])}},
+		Actuators:        []nv.ActuatorSpec{{Name: "actuator", VectorLength: len(samples[0].ExpectedOutputs[0])}},
+		Template:         nv.TEMPLATE_HIDDEN_LAYER,
+		HiddenLayerWidth: hiddenLayerWidth,
+	}
+	return Task{
+		Name: name,
+		NewScape: func() nv.Scape {
+			return nv.NewTrainingSampleScape(samples)
+		},
+		NewCortex:        factory.NewCortex,
+		FitnessThreshold: fitnessThreshold,
+	}
+}
+
+func sample(inputs, outputs []float64) *ng.TrainingSample {
+	return &ng.TrainingSample{
+		SampleInputs:    [][]float64{inputs},
+		ExpectedOutputs: [][]float64{outputs},
+	}
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"github.com/couchbaselabs/logg"
+	nv "github.com/maxxk/neurvolve"
+	"github.com/maxxk/neurvolve/benchmark"
+	"os"
+	"strings"
+)
+
+func init() {
+	logg.LogKeys["MAIN"] = true
+	logg.LogKeys["DEBUG"] = false
+	logg.LogKeys["NEURVOLVE"] = false
+	logg.LogKeys["NODE_STATE"] = false
+}
+
+// Run an experiment described by a json or yaml file, eg:
+// $ go run cmd/neurvolve/main.go -experiment examples/experiments/xnor_population.json
+//
+// or the benchmark suite, eg:
+// $ go run cmd/neurvolve/main.go benchmark -tasks xor,sine -trainers population -seeds 5
+func main() {
+
+	if len(os.Args) > 1 && os.Args[1] == "benchmark" {
+		runBenchmark(os.Args[2:])
+		return
+	}
+
+	experimentFile := flag.String("experiment", "", "Path to a .json, .yaml or .yml experiment spec")
+	list := flag.Bool("list", false, "List the names of all registered components")
+	flag.Parse()
+
+	registry := nv.DefaultRegistry()
+
+	if *list {
+		names, _ := json.MarshalIndent(registry.Names(), "", "  ")
+		fmt.Println(string(names))
+		return
+	}
+
+	if *experimentFile == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	spec, err := nv.LoadExperimentSpec(*experimentFile)
+	if err != nil {
+		logg.LogFatal("Unable to load experiment: %v", err)
+	}
+
+	succeeded, err := spec.Run(registry)
+	if err != nil {
+		logg.LogFatal("Unable to run experiment: %v", err)
+	}
+	if !succeeded {
+		os.Exit(1)
+	}
+
+}
+
+func runBenchmark(args []string) {
+
+	flags := flag.NewFlagSet("benchmark", flag.ExitOnError)
+	taskNames := flags.String("tasks", "", "Comma separated tasks to run, defaults to all")
+	trainerNames := flags.String("trainers", "", "Comma separated trainers to run, defaults to all")
+	numSeeds := flags.Int("seeds", 10, "Number of seeds to run each trainer on each task with")
+	maxEvaluations := flags.Int("max-evaluations", 0, "Maximum evaluations of each run, defaults to no limit")
+	flags.Parse(args)
+
+	tasks := benchmark.StandardTasks()
+	if *taskNames != "" {
+		tasks = nil
+		for _, name := range strings.Split(*taskNames, ",") {
+			task, ok := benchmark.FindTask(name)
+			if !ok {
+				logg.LogFatal("Unknown task: %q", name)
+			}
+			tasks = append(tasks, task)
+		}
+	}
+
+	trainers := benchmark.StandardTrainers()
+	if *trainerNames != "" {
+		trainers = nil
+		for _, name := range strings.Split(*trainerNames, ",") {
+			trainer, ok := benchmark.FindTrainer(name)
+			if !ok {
+				logg.LogFatal("Unknown trainer: %q", name)
+			}
+			trainers = append(trainers, trainer)
+		}
+	}
+
+	// the trainers are rather chatty
+	logg.LogKeys["MAIN"] = false
+
+	results := benchmark.Run(tasks, trainers, *numSeeds, *maxEvaluations)
+	benchmark.WriteTable(os.Stdout, benchmark.Summarize(results))
+
+}
@@ -0,0 +1,246 @@
+package neurvolve
+
+import (
+	"encoding/binary"
+	"fmt"
+	"github.com/couchbaselabs/logg"
+	ng "github.com/maxxk/neurgo"
+	"hash"
+	"hash/fnv"
+	"math"
+	"sync"
+)
+
+// Number of compiled cortexes kept by a compiledCortexCache before it
+// starts over
+const COMPILED_CORTEX_CACHE_SIZE = 1000
+
+// A feed-forward cortex flattened into arrays, which evaluates samples
+// synchronously instead of passing messages between goroutines.
+// It is a snapshot: changes to the cortex after compiling are not seen.
+//
+// All sensor and neuron outputs live in a single array of values.  The
+// sensor inputs come first, followed by one value per neuron in
+// topological order, so each neuron only reads values computed before it.
+type CompiledCortex struct {
+	sensorOffsets   []int
+	sensorLengths   []int
+	numSensorValues int
+	neurons         []compiledNeuron
+
+	// For each actuator, the indexes of the values it collects
+	actuatorInputs [][]int
+}
+
+type compiledNeuron struct {
+	activation ng.ActivationFunction
+	bias       float64
+	inputs     []int
+	weights    []float64
+}
+
+// Flatten the cortex, which fails if it is recurrent
+func CompileCortex(cortex *ng.Cortex) (*CompiledCortex, error) {
+
+	compiled := &CompiledCortex{}
+	valueIndexes := make(map[string]int)
+	vectorLengths := make(map[string]int)
+
+	offset := 0
+	for _, sensor := range cortex.Sensors {
+		valueIndexes[sensor.NodeId.UUID] = offset
+		vectorLengths[sensor.NodeId.UUID] = sensor.VectorLength
+		compiled.sensorOffsets = append(compiled.sensorOffsets, offset)
+		compiled.sensorLengths = append(compiled.sensorLengths, sensor.VectorLength)
+		offset += sensor.VectorLength
+	}
+	compiled.numSensorValues = offset
+
+	neurons, err := topologicalOrder(cortex)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, neuron := range neurons {
+
+		if neuron.ActivationFunction == nil || neuron.ActivationFunction.ActivationFunction == nil {
+			return nil, fmt.Errorf("Neuron %v has no activation function", neuron.NodeId.UUID)
+		}
+
+		compiledNeuron := compiledNeuron{
+			activation: neuron.ActivationFunction.ActivationFunction,
+			bias:       neuron.Bias,
+		}
+		for _, inbound := range neuron.Inbound {
+			sourceIndex := valueIndexes[inbound.NodeId.UUID]
+			if len(inbound.Weights) != vectorLengths[inbound.NodeId.UUID] {
+				return nil, fmt.Errorf("Neuron %v has %d weights for %v, expected %d", neuron.NodeId.UUID, len(inbound.Weights), inbound.NodeId.UUID, vectorLengths[inbound.NodeId.UUID])
+			}
+			for j, weight := range inbound.Weights {
+				compiledNeuron.inputs = append(compiledNeuron.inputs, sourceIndex+j)
+				compiledNeuron.weights = append(compiledNeuron.weights, weight)
+			}
+		}
+		compiled.neurons = append(compiled.neurons, compiledNeuron)
+		valueIndexes[neuron.NodeId.UUID] = compiled.numSensorValues + i
+		vectorLengths[neuron.NodeId.UUID] = 1
+
+	}
+
+	for _, actuator := range cortex.Actuators {
+		inputs := make([]int, 0, len(actuator.Inbound))
+		for _, inbound := range actuator.Inbound {
+			index, ok := valueIndexes[inbound.NodeId.UUID]
+			if !ok || index < compiled.numSensorValues {
+				return nil, fmt.Errorf("Actuator %v has an inbound connection from unknown neuron %v", actuator.NodeId.UUID, inbound.NodeId.UUID)
+			}
+			inputs = append(inputs, index)
+		}
+		compiled.actuatorInputs = append(compiled.actuatorInputs, inputs)
+	}
+
+	return compiled, nil
+
+}
+
+// Compute the outputs of each actuator from the inputs of each sensor,
+// in the layout of ng.TrainingSample
+func (compiled *CompiledCortex) Compute(inputs [][]float64) [][]float64 {
+	values := compiled.newValues()
+	return compiled.compute(inputs, values)
+}
+
+// Same as ng.Cortex.Fitness: the inverse of the average sum of squares
+// error over the samples
+func (compiled *CompiledCortex) Fitness(samples []*ng.TrainingSample) float64 {
+
+	values := compiled.newValues()
+	errorAccumulated := 0.0
+	for _, sample := range samples {
+		outputs := compiled.compute(sample.SampleInputs, values)
+		for i, output := range outputs {
+			errorAccumulated += ng.SumOfSquaresError(sample.ExpectedOutputs[i], output)
+		}
+	}
+	avgError := errorAccumulated / float64(len(samples))
+	return 1 / avgError
+
+}
+
+func (compiled *CompiledCortex) newValues() []float64 {
+	return make([]float64, compiled.numSensorValues+len(compiled.neurons))
+}
+
+func (compiled *CompiledCortex) compute(inputs [][]float64, values []float64) [][]float64 {
+
+	if len(inputs) != len(compiled.sensorLengths) {
+		logg.LogPanic("Expected %d input vectors, got %d", len(compiled.sensorLengths), len(inputs))
+	}
+	for i, input := range inputs {
+		if len(input) != compiled.sensorLengths[i] {
+			logg.LogPanic("Expected input vector %d to have length %d, got %d", i, compiled.sensorLengths[i], len(input))
+		}
+		copy(values[compiled.sensorOffsets[i]:], input)
+	}
+
+	for i, neuron := range compiled.neurons {
+		sum := 0.0
+		for j, inputIndex := range neuron.inputs {
+			sum += neuron.weights[j] * values[inputIndex]
+		}
+		values[compiled.numSensorValues+i] = neuron.activation(sum + neuron.bias)
+	}
+
+	outputs := make([][]float64, len(compiled.actuatorInputs))
+	for i, actuatorInputs := range compiled.actuatorInputs {
+		output := make([]float64, len(actuatorInputs))
+		for j, index := range actuatorInputs {
+			output[j] = values[index]
+		}
+		outputs[i] = output
+	}
+	return outputs
+
+}
+
+// Compiled forms of the cortexes evaluated so far, so that a cortex which
+// is evaluated again, eg a survivor of the PopulationTrainer, isn't
+// compiled again.  Entries are keyed by cortex and checked against a
+// fingerprint of its topology and parameters, since cortexes may be
+// modified in place, eg by the hill climber.
+type compiledCortexCache struct {
+	mutex   sync.Mutex
+	entries map[*ng.Cortex]compiledCortexEntry
+}
+
+type compiledCortexEntry struct {
+	fingerprint uint64
+	compiled    *CompiledCortex
+	err         error
+}
+
+// Same as CompileCortex, but only compiles a cortex again if it changed
+func (cache *compiledCortexCache) compile(cortex *ng.Cortex) (*CompiledCortex, error) {
+
+	fingerprint := cortexFingerprint(cortex)
+
+	cache.mutex.Lock()
+	entry, ok := cache.entries[cortex]
+	cache.mutex.Unlock()
+	if ok && entry.fingerprint == fingerprint {
+		return entry.compiled, entry.err
+	}
+
+	compiled, err := CompileCortex(cortex)
+
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+	if cache.entries == nil || len(cache.entries) >= COMPILED_CORTEX_CACHE_SIZE {
+		cache.entries = make(map[*ng.Cortex]compiledCortexEntry)
+	}
+	cache.entries[cortex] = compiledCortexEntry{fingerprint: fingerprint, compiled: compiled, err: err}
+	return compiled, err
+
+}
+
+// Hash of everything CompileCortex reads from the cortex
+func cortexFingerprint(cortex *ng.Cortex) uint64 {
+
+	h := fnv.New64a()
+	for _, sensor := range cortex.Sensors {
+		hashString(h, sensor.NodeId.UUID)
+		hashFloat(h, float64(sensor.VectorLength))
+	}
+	for _, neuron := range cortex.Neurons {
+		hashString(h, neuron.NodeId.UUID)
+		if neuron.ActivationFunction != nil {
+			hashString(h, neuron.ActivationFunction.Name)
+		}
+		hashFloat(h, neuron.Bias)
+		for _, inbound := range neuron.Inbound {
+			hashString(h, inbound.NodeId.UUID)
+			for _, weight := range inbound.Weights {
+				hashFloat(h, weight)
+			}
+		}
+	}
+	for _, actuator := range cortex.Actuators {
+		hashString(h, actuator.NodeId.UUID)
+		for _, inbound := range actuator.Inbound {
+			hashString(h, inbound.NodeId.UUID)
+		}
+	}
+	return h.Sum64()
+
+}
+
+func hashString(h hash.Hash64, value string) {
+	h.Write([]byte(value))
+	h.Write([]byte{0})
+}
+
+func hashFloat(h hash.Hash64, value float64) {
+	var buffer [8]byte
+	binary.LittleEndian.PutUint64(buffer[:], math.Float64bits(value))
+	h.Write(buffer[:])
+}
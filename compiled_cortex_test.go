@@ -0,0 +1,101 @@
+package neurvolve
+
+import (
+	"github.com/couchbaselabs/go.assert"
+	ng "github.com/maxxk/neurgo"
+	"math"
+	"testing"
+)
+
+func TestCompiledCortexCompute(t *testing.T) {
+
+	compiled, err := CompileCortex(SingleNeuronCortex("cortex"))
+	assert.True(t, err == nil)
+
+	outputs := compiled.Compute([][]float64{{2.5}})
+	assert.Equals(t, len(outputs), 1)
+	assert.Equals(t, len(outputs[0]), 1)
+	assert.Equals(t, outputs[0][0], 3.5)
+
+	// BasicCortex is a chain of four sigmoid neurons
+	sigmoid := func(x float64) float64 {
+		return 1.0 / (1.0 + math.Exp(-x))
+	}
+	compiled, err = CompileCortex(BasicCortex())
+	assert.True(t, err == nil)
+	for _, input := range [][]float64{{-1, -1}, {-1, 1}, {0.5, 1}} {
+		hidden := sigmoid(sigmoid(sigmoid(20*input[0]+20*input[1]-30)+10) + 10)
+		expected := sigmoid(hidden - 10)
+		output := compiled.Compute([][]float64{input})[0][0]
+		assert.True(t, math.Abs(output-expected) < 1e-12)
+	}
+
+}
+
+func TestCompileCortexRecurrent(t *testing.T) {
+
+	_, err := CompileCortex(BasicCortexRecurrent())
+	assert.True(t, err != nil)
+
+}
+
+// The compiled cortex must give the same fitness as running the cortex
+func TestCompiledCortexConformance(t *testing.T) {
+
+	examples := ng.XnorTrainingSamples()
+
+	cortexes := []*ng.Cortex{
+		BasicCortex(),
+		ng.XnorCortex(),
+		ng.XnorCortexUntrained(),
+	}
+	for i := 0; i < 5; i++ {
+		cortex := BasicCortex()
+		PerturbParameters(cortex, []float64{-10 * math.Pi, 10 * math.Pi})
+		cortexes = append(cortexes, cortex)
+	}
+
+	for _, cortex := range cortexes {
+		compiled, err := CompileCortex(cortex)
+		assert.True(t, err == nil)
+		expected := cortex.Fitness(examples)
+		actual := compiled.Fitness(examples)
+		assert.True(t, math.Abs(expected-actual) <= 1e-9*math.Max(1, math.Abs(expected)))
+	}
+
+}
+
+func TestTrainingSampleScapeUseCompiledCortex(t *testing.T) {
+
+	examples := ng.XnorTrainingSamples()
+	scape := TrainingSampleScape{examples: examples, UseCompiledCortex: true}
+
+	cortex := BasicCortex()
+	fitness := scape.Fitness(cortex)
+	assert.True(t, math.Abs(fitness-cortex.Fitness(examples)) <= 1e-9*math.Max(1, fitness))
+
+	// falls back to running recurrent cortexes
+	recurrent := BasicCortexRecurrent()
+	assert.Equals(t, scape.Fitness(recurrent), recurrent.Fitness(examples))
+
+}
+
+func TestCompiledCortexCache(t *testing.T) {
+
+	cache := &compiledCortexCache{}
+	cortex := BasicCortex()
+
+	compiled, err := cache.compile(cortex)
+	assert.True(t, err == nil)
+	again, _ := cache.compile(cortex)
+	assert.True(t, again == compiled)
+
+	// modified in place, so compiled again
+	cortex.Neurons[0].Bias += 1
+	modified, _ := cache.compile(cortex)
+	assert.True(t, modified != compiled)
+
+	_, err = cache.compile(BasicCortexRecurrent())
+	assert.True(t, err != nil)
+
+}
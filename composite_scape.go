@@ -0,0 +1,183 @@
+package neurvolve
+
+import (
+	"github.com/couchbaselabs/logg"
+	ng "github.com/maxxk/neurgo"
+	"math"
+)
+
+// Evaluates cortexes on several scapes and adds up their weighted
+// fitness, to evolve cortexes which do well on all of them
+type WeightedSumScape struct {
+	Scapes []Scape
+
+	// Weight of each scape, defaults to 1 for all
+	Weights []float64
+}
+
+// Evaluates cortexes on several scapes and scores them on the one they
+// do worst on
+type MinScape struct {
+	Scapes []Scape
+}
+
+// Evaluates cortexes on a sequence of scapes, only moving on to the next
+// scape once a cortex reaches the threshold of the current one.  A cortex
+// which reached scape i scores the sum of the thresholds of the scapes
+// before it plus its fitness on scape i, so it always beats the cortexes
+// which didn't, as long as fitness is never negative.
+type StagedScape struct {
+	Scapes []Scape
+
+	// Fitness needed on each scape to move on to the next one, so there
+	// is one less of these than of Scapes
+	Thresholds []float64
+}
+
+func (scape WeightedSumScape) Fitness(cortex *ng.Cortex) float64 {
+	return scape.weightedSum(func(s Scape) float64 {
+		return s.Fitness(cortex)
+	})
+}
+
+func (scape WeightedSumScape) FitnessAgainst(cortex *ng.Cortex, opponent *ng.Cortex) float64 {
+	return scape.weightedSum(func(s Scape) float64 {
+		return s.FitnessAgainst(cortex, opponent)
+	})
+}
+
+func (scape WeightedSumScape) StartGeneration(generation int, bestFitness float64) {
+	startGeneration(scape.Scapes, generation, bestFitness)
+}
+
+func (scape WeightedSumScape) Sampled() bool {
+	return anySampled(scape.Scapes)
+}
+
+func (scape WeightedSumScape) FullFitness(cortex *ng.Cortex) float64 {
+	return scape.weightedSum(func(s Scape) float64 {
+		return fullFitness(s, cortex)
+	})
+}
+
+func (scape WeightedSumScape) weightedSum(fitness func(Scape) float64) float64 {
+	if scape.Weights != nil && len(scape.Weights) != len(scape.Scapes) {
+		logg.LogPanic("Got %d weights for %d scapes", len(scape.Weights), len(scape.Scapes))
+	}
+	sum := 0.0
+	for i, s := range scape.Scapes {
+		weight := 1.0
+		if scape.Weights != nil {
+			weight = scape.Weights[i]
+		}
+		sum += weight * fitness(s)
+	}
+	return sum
+}
+
+func (scape MinScape) Fitness(cortex *ng.Cortex) float64 {
+	return scape.min(func(s Scape) float64 {
+		return s.Fitness(cortex)
+	})
+}
+
+func (scape MinScape) FitnessAgainst(cortex *ng.Cortex, opponent *ng.Cortex) float64 {
+	return scape.min(func(s Scape) float64 {
+		return s.FitnessAgainst(cortex, opponent)
+	})
+}
+
+func (scape MinScape) StartGeneration(generation int, bestFitness float64) {
+	startGeneration(scape.Scapes, generation, bestFitness)
+}
+
+func (scape MinScape) Sampled() bool {
+	return anySampled(scape.Scapes)
+}
+
+func (scape MinScape) FullFitness(cortex *ng.Cortex) float64 {
+	return scape.min(func(s Scape) float64 {
+		return fullFitness(s, cortex)
+	})
+}
+
+func (scape MinScape) min(fitness func(Scape) float64) float64 {
+	if len(scape.Scapes) == 0 {
+		logg.LogPanic("MinScape needs at least one scape")
+	}
+	min := math.Inf(1)
+	for _, s := range scape.Scapes {
+		min = math.Min(min, fitness(s))
+	}
+	return min
+}
+
+func (scape StagedScape) Fitness(cortex *ng.Cortex) float64 {
+	return scape.staged(func(s Scape) float64 {
+		return s.Fitness(cortex)
+	})
+}
+
+func (scape StagedScape) FitnessAgainst(cortex *ng.Cortex, opponent *ng.Cortex) float64 {
+	return scape.staged(func(s Scape) float64 {
+		return s.FitnessAgainst(cortex, opponent)
+	})
+}
+
+func (scape StagedScape) StartGeneration(generation int, bestFitness float64) {
+	startGeneration(scape.Scapes, generation, bestFitness)
+}
+
+func (scape StagedScape) Sampled() bool {
+	return anySampled(scape.Scapes)
+}
+
+func (scape StagedScape) FullFitness(cortex *ng.Cortex) float64 {
+	return scape.staged(func(s Scape) float64 {
+		return fullFitness(s, cortex)
+	})
+}
+
+// The fitness threshold to give a trainer for a cortex to pass every
+// stage, given the threshold of the last scape
+func (scape StagedScape) FitnessThreshold(lastThreshold float64) float64 {
+	sum := lastThreshold
+	for _, threshold := range scape.Thresholds {
+		sum += threshold
+	}
+	return sum
+}
+
+func (scape StagedScape) staged(fitness func(Scape) float64) float64 {
+	if len(scape.Scapes) == 0 || len(scape.Thresholds) != len(scape.Scapes)-1 {
+		logg.LogPanic("Need one less threshold than the %d scapes", len(scape.Scapes))
+	}
+	passed := 0.0
+	for i, s := range scape.Scapes {
+		f := fitness(s)
+		if i == len(scape.Thresholds) || f < scape.Thresholds[i] {
+			return passed + f
+		}
+		passed += scape.Thresholds[i]
+	}
+	return passed
+}
+
+// Pass the start of a generation on to the scapes which care about it
+func startGeneration(scapes []Scape, generation int, bestFitness float64) {
+	for _, scape := range scapes {
+		if generationalScape, ok := scape.(GenerationalScape); ok {
+			generationalScape.StartGeneration(generation, bestFitness)
+		}
+	}
+}
+
+// Whether any of the scapes scores the current generation on a sample
+func anySampled(scapes []Scape) bool {
+	for _, scape := range scapes {
+		if isSampled(scape) {
+			return true
+		}
+	}
+	return false
+}
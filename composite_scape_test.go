@@ -0,0 +1,70 @@
+package neurvolve
+
+import (
+	"github.com/couchbaselabs/go.assert"
+	ng "github.com/maxxk/neurgo"
+	"testing"
+)
+
+func TestWeightedSumScape(t *testing.T) {
+
+	cortex := SingleNeuronCortex("cortex")
+	scapes := []Scape{ConstantScape{1}, ConstantScape{2}}
+
+	assert.Equals(t, WeightedSumScape{Scapes: scapes}.Fitness(cortex), 3.0)
+
+	scape := WeightedSumScape{Scapes: scapes, Weights: []float64{0.5, 2}}
+	assert.Equals(t, scape.Fitness(cortex), 4.5)
+	assert.Equals(t, scape.FitnessAgainst(cortex, SingleNeuronCortex("opponent")), 4.5)
+
+}
+
+func TestMinScape(t *testing.T) {
+
+	cortex := SingleNeuronCortex("cortex")
+	scape := MinScape{Scapes: []Scape{ConstantScape{3}, ConstantScape{-1}, ConstantScape{2}}}
+	assert.Equals(t, scape.Fitness(cortex), -1.0)
+
+}
+
+func TestStagedScape(t *testing.T) {
+
+	cortex := SingleNeuronCortex("cortex")
+
+	// fails the first stage, so the second is never evaluated
+	counting := &CountingScape{}
+	scape := StagedScape{
+		Scapes:     []Scape{ConstantScape{0.5}, counting},
+		Thresholds: []float64{1},
+	}
+	assert.Equals(t, scape.Fitness(cortex), 0.5)
+	assert.Equals(t, counting.numEvaluations, 0)
+
+	// passes the first two stages, and scores their thresholds plus
+	// its fitness on the last one
+	scape = StagedScape{
+		Scapes:     []Scape{ConstantScape{5}, ConstantScape{2}, counting},
+		Thresholds: []float64{1, 2},
+	}
+	assert.Equals(t, scape.Fitness(cortex), 4.0)
+	assert.Equals(t, counting.numEvaluations, 1)
+	assert.Equals(t, scape.FitnessThreshold(10), 13.0)
+
+}
+
+func TestCompositeScapeStartGeneration(t *testing.T) {
+
+	recording := &GenerationRecordingScape{ConstantScape: ConstantScape{1}}
+	scape := WeightedSumScape{Scapes: []Scape{ConstantScape{1}, recording}}
+
+	pt := &PopulationTrainer{
+		FitnessThreshold: 1000,
+		MaxGenerations:   2,
+		CortexMutator:    NoOpMutator,
+	}
+	population := []*ng.Cortex{SingleNeuronCortex("cortex1"), SingleNeuronCortex("cortex2")}
+	pt.Train(population, scape, NewNullRecorder())
+
+	assert.Equals(t, len(recording.generations), 2)
+
+}
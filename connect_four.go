@@ -0,0 +1,102 @@
+package neurvolve
+
+const (
+	CONNECT_FOUR_ROWS    = 6
+	CONNECT_FOUR_COLUMNS = 7
+)
+
+// Connect four for cortexes with a sensor of length 42, one element per
+// cell in row major order starting from the bottom row, and an actuator
+// of length 7, one element per column.  Cells are encoded like the
+// squares of TicTacToe, and a move is the index of a column.
+type ConnectFour struct{}
+
+type connectFourState struct {
+	// 0 for empty, otherwise the player who owns the cell + 1
+	board    [CONNECT_FOUR_ROWS * CONNECT_FOUR_COLUMNS]int
+	toMove   int
+	numMoves int
+
+	// Cell of the last move, or -1 at the start
+	lastCell int
+}
+
+func (game ConnectFour) InitialState() GameState {
+	return connectFourState{lastCell: -1}
+}
+
+func (game ConnectFour) PlayerToMove(state GameState) int {
+	return state.(connectFourState).toMove
+}
+
+func (game ConnectFour) Encode(state GameState) [][]float64 {
+	s := state.(connectFourState)
+	return [][]float64{encodeBoard(s.board[:], s.toMove)}
+}
+
+func (game ConnectFour) LegalMoves(state GameState) []int {
+	s := state.(connectFourState)
+	moves := make([]int, 0)
+	for column := 0; column < CONNECT_FOUR_COLUMNS; column++ {
+		if s.board[connectFourCell(CONNECT_FOUR_ROWS-1, column)] == 0 {
+			moves = append(moves, column)
+		}
+	}
+	return moves
+}
+
+func (game ConnectFour) DecodeMove(state GameState, outputs [][]float64) int {
+	return DecodeBestLegalMove(outputs[0], game.LegalMoves(state))
+}
+
+// Drop a piece in the column
+func (game ConnectFour) Play(state GameState, move int) GameState {
+	s := state.(connectFourState)
+	for row := 0; row < CONNECT_FOUR_ROWS; row++ {
+		cell := connectFourCell(row, move)
+		if s.board[cell] == 0 {
+			s.board[cell] = s.toMove + 1
+			s.lastCell = cell
+			break
+		}
+	}
+	s.toMove = 1 - s.toMove
+	s.numMoves += 1
+	return s
+}
+
+func (game ConnectFour) Score(state GameState) (over bool, scores [2]float64) {
+	s := state.(connectFourState)
+	if s.lastCell >= 0 && s.connectsFour(s.lastCell) {
+		return true, gameScores(s.board[s.lastCell] - 1)
+	}
+	if s.numMoves == len(s.board) {
+		return true, gameScores(-1)
+	}
+	return false, scores
+}
+
+// Whether the piece in the cell is part of a line of four or more
+func (s connectFourState) connectsFour(cell int) bool {
+	row, column := cell/CONNECT_FOUR_COLUMNS, cell%CONNECT_FOUR_COLUMNS
+	owner := s.board[cell]
+	directions := [][2]int{{0, 1}, {1, 0}, {1, 1}, {1, -1}}
+	for _, direction := range directions {
+		length := 1
+		for _, sign := range []int{1, -1} {
+			r, c := row+sign*direction[0], column+sign*direction[1]
+			for r >= 0 && r < CONNECT_FOUR_ROWS && c >= 0 && c < CONNECT_FOUR_COLUMNS && s.board[connectFourCell(r, c)] == owner {
+				length += 1
+				r, c = r+sign*direction[0], c+sign*direction[1]
+			}
+		}
+		if length >= 4 {
+			return true
+		}
+	}
+	return false
+}
+
+func connectFourCell(row, column int) int {
+	return row*CONNECT_FOUR_COLUMNS + column
+}
@@ -0,0 +1,55 @@
+package neurvolve
+
+import (
+	ng "github.com/maxxk/neurgo"
+)
+
+// Runs a cortex one step at a time, for scapes where each input depends
+// on the previous outputs.  The cortex keeps its recurrent state from
+// one step to the next until it is stopped.
+type CortexStepper struct {
+	cortex  *ng.Cortex
+	inputs  [][]float64
+	outputs [][]float64
+}
+
+// Start running the cortex.  It must not be run elsewhere until the
+// stepper is stopped.
+func StartCortex(cortex *ng.Cortex) *CortexStepper {
+
+	stepper := &CortexStepper{cortex: cortex}
+
+	cortex.Init()
+	for i, sensor := range cortex.Sensors {
+		sensorIndex := i
+		sensor.SensorFunction = func(iteration int) []float64 {
+			return stepper.inputs[sensorIndex]
+		}
+	}
+	for i, actuator := range cortex.Actuators {
+		actuatorIndex := i
+		actuatorNodeId := actuator.NodeId
+		actuator.ActuatorFunction = func(outputVector []float64) {
+			stepper.outputs[actuatorIndex] = append([]float64{}, outputVector...)
+			cortex.SyncChan <- actuatorNodeId
+		}
+	}
+	cortex.Run()
+
+	return stepper
+
+}
+
+// Feed the inputs of each sensor to the cortex and return the outputs
+// of each actuator
+func (stepper *CortexStepper) Step(inputs [][]float64) [][]float64 {
+	stepper.inputs = inputs
+	stepper.outputs = make([][]float64, len(stepper.cortex.Actuators))
+	stepper.cortex.SyncSensors()
+	stepper.cortex.SyncActuators()
+	return stepper.outputs
+}
+
+func (stepper *CortexStepper) Stop() {
+	stepper.cortex.Shutdown()
+}
@@ -0,0 +1,256 @@
+package neurvolve
+
+import (
+	"net/http"
+)
+
+// Serves the dashboard, a single self-contained html page which
+// gets everything it shows from the json endpoints.  It uses relative
+// urls, so it also works when the handlers are mounted under a prefix.
+func DashboardHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write([]byte(dashboardHtml))
+}
+
+const dashboardHtml = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>neurvolve</title>
+<style>
+body { font-family: sans-serif; margin: 1em; color: #222; }
+h1 { font-size: 1.4em; margin: 0 0 .5em 0; }
+h2 { font-size: 1.1em; }
+#layout { display: flex; gap: 1.5em; align-items: flex-start; }
+#population { min-width: 26em; }
+table { border-collapse: collapse; font-size: .9em; }
+th, td { padding: .2em .6em; text-align: left; border-bottom: 1px solid #ddd; }
+tr.selected { background: #e8f0ff; }
+td.uuid { font-family: monospace; }
+a { color: #2255cc; cursor: pointer; text-decoration: underline; }
+#compare { display: flex; gap: 1em; }
+.pane { flex: 1; border: 1px solid #ccc; padding: .5em; min-width: 20em; }
+.pane .svg { overflow: auto; max-height: 40em; }
+.status { color: #777; font-size: .9em; }
+#chart { border: 1px solid #ccc; }
+.legend span { margin-right: 1em; }
+</style>
+</head>
+<body>
+<h1>neurvolve</h1>
+<div class="status">
+  Generation <span id="generation">-</span>
+  &middot; <label><input type="checkbox" id="autorefresh" checked> auto refresh</label>
+  &middot; <span id="message"></span>
+</div>
+
+<h2>Fitness over generations</h2>
+<svg id="chart" width="800" height="240"></svg>
+<div class="legend">
+  <span style="color:#2255cc">&#9632; best</span>
+  <span style="color:#22aa55">&#9632; mean</span>
+  <span style="color:#cc7722">&#9632; median</span>
+</div>
+
+<div id="layout">
+  <div id="population">
+    <h2>Population</h2>
+    <table>
+      <thead><tr><th>#</th><th>uuid</th><th>fitness</th><th>neurons</th><th>parent</th><th>born</th><th>compare</th></tr></thead>
+      <tbody id="cortexes"></tbody>
+    </table>
+  </div>
+  <div>
+    <h2>Compare</h2>
+    <div id="compare">
+      <div class="pane" id="pane0"></div>
+      <div class="pane" id="pane1"></div>
+    </div>
+  </div>
+</div>
+
+<script>
+var population = [];
+var selected = [null, null];
+
+function byId(id) { return document.getElementById(id); }
+
+function text(tag, content) {
+  var el = document.createElement(tag);
+  el.textContent = content;
+  return el;
+}
+
+function uuidOf(evaldCortex) { return evaldCortex.Cortex.NodeId.UUID; }
+
+function find(uuid) {
+  for (var i = 0; i < population.length; i++) {
+    if (uuidOf(population[i]) === uuid) { return population[i]; }
+  }
+  return null;
+}
+
+function select(pane, uuid) {
+  selected[pane] = uuid;
+  renderPopulation();
+  renderPane(pane);
+}
+
+function renderPopulation() {
+  var tbody = byId("cortexes");
+  tbody.innerHTML = "";
+  population.forEach(function(evaldCortex, i) {
+    var uuid = uuidOf(evaldCortex);
+    var tr = document.createElement("tr");
+    if (selected.indexOf(uuid) >= 0) { tr.className = "selected"; }
+    tr.appendChild(text("td", i + 1));
+    var uuidCell = text("td", uuid);
+    uuidCell.className = "uuid";
+    tr.appendChild(uuidCell);
+    tr.appendChild(text("td", evaldCortex.Fitness.toPrecision(6)));
+    tr.appendChild(text("td", (evaldCortex.Cortex.Neurons || []).length));
+    tr.appendChild(parentCell(evaldCortex, 0));
+    tr.appendChild(text("td", evaldCortex.CreatedInGeneration));
+    var compare = document.createElement("td");
+    [0, 1].forEach(function(pane) {
+      var link = text("a", pane === 0 ? "left" : "right");
+      link.onclick = function() { select(pane, uuid); };
+      compare.appendChild(link);
+      compare.appendChild(document.createTextNode(" "));
+    });
+    tr.appendChild(compare);
+    tbody.appendChild(tr);
+  });
+}
+
+function parentCell(evaldCortex, pane) {
+  var td = document.createElement("td");
+  var parentId = evaldCortex.ParentId;
+  if (!parentId || parentId === uuidOf(evaldCortex)) {
+    td.textContent = "-";
+  } else if (find(parentId)) {
+    var link = text("a", parentId);
+    link.onclick = function() { select(pane, parentId); };
+    td.appendChild(link);
+  } else {
+    td.textContent = parentId + " (culled)";
+  }
+  td.className = "uuid";
+  return td;
+}
+
+function renderPane(pane) {
+  var div = byId("pane" + pane);
+  div.innerHTML = "";
+  var uuid = selected[pane];
+  div.setAttribute("data-uuid", uuid && find(uuid) ? uuid : "");
+  if (!uuid) {
+    div.appendChild(text("p", "Choose a cortex from the population"));
+    return;
+  }
+  var evaldCortex = find(uuid);
+  if (!evaldCortex) {
+    div.appendChild(text("p", uuid + " is no longer in the population"));
+    return;
+  }
+  div.appendChild(text("h3", uuid));
+  var details = document.createElement("table");
+  [["fitness", evaldCortex.Fitness],
+   ["neurons", (evaldCortex.Cortex.Neurons || []).length],
+   ["created in generation", evaldCortex.CreatedInGeneration]].forEach(function(row) {
+    var tr = document.createElement("tr");
+    tr.appendChild(text("th", row[0]));
+    tr.appendChild(text("td", row[1]));
+    details.appendChild(tr);
+  });
+  var parentRow = document.createElement("tr");
+  parentRow.appendChild(text("th", "parent"));
+  parentRow.appendChild(parentCell(evaldCortex, pane));
+  details.appendChild(parentRow);
+  div.appendChild(details);
+
+  var svg = document.createElement("div");
+  svg.className = "svg";
+  div.appendChild(svg);
+  fetch("cortex/" + encodeURIComponent(uuid) + "/svg")
+    .then(function(response) { return response.text(); })
+    .then(function(body) { svg.innerHTML = body; });
+}
+
+function renderChart(history) {
+  var chart = byId("chart");
+  var width = chart.getAttribute("width"), height = chart.getAttribute("height");
+  var margin = 40;
+  chart.innerHTML = "";
+  if (!history || history.length === 0) { return; }
+
+  var series = [["BestFitness", "#2255cc"], ["MeanFitness", "#22aa55"], ["MedianFitness", "#cc7722"]];
+  var min = Infinity, max = -Infinity;
+  history.forEach(function(stats) {
+    series.forEach(function(s) {
+      var value = stats[s[0]];
+      if (isFinite(value)) { min = Math.min(min, value); max = Math.max(max, value); }
+    });
+  });
+  if (min === max) { max = min + 1; }
+  var first = history[0].Generation, last = history[history.length - 1].Generation;
+  var span = Math.max(last - first, 1);
+
+  function x(generation) { return margin + (generation - first) / span * (width - 2 * margin); }
+  function y(value) { return height - margin - (value - min) / (max - min) * (height - 2 * margin); }
+
+  var ns = "http://www.w3.org/2000/svg";
+  function add(tag, attrs, content) {
+    var el = document.createElementNS(ns, tag);
+    for (var key in attrs) { el.setAttribute(key, attrs[key]); }
+    if (content !== undefined) { el.textContent = content; }
+    chart.appendChild(el);
+  }
+
+  add("line", {x1: margin, y1: height - margin, x2: width - margin, y2: height - margin, stroke: "#999"});
+  add("line", {x1: margin, y1: margin, x2: margin, y2: height - margin, stroke: "#999"});
+  add("text", {x: 2, y: margin, "font-size": 10}, max.toPrecision(4));
+  add("text", {x: 2, y: height - margin, "font-size": 10}, min.toPrecision(4));
+  add("text", {x: margin, y: height - margin + 15, "font-size": 10}, first);
+  add("text", {x: width - margin, y: height - margin + 15, "font-size": 10}, last);
+
+  series.forEach(function(s) {
+    var points = history.filter(function(stats) { return isFinite(stats[s[0]]); })
+      .map(function(stats) { return x(stats.Generation) + "," + y(stats[s[0]]); });
+    add("polyline", {points: points.join(" "), fill: "none", stroke: s[1], "stroke-width": 1.5});
+  });
+}
+
+function refresh() {
+  fetch("cortex")
+    .then(function(response) {
+      if (!response.ok) { throw new Error("population: " + response.status); }
+      return response.json();
+    })
+    .then(function(snapshot) {
+      population = (snapshot.Population || []).slice().sort(function(a, b) { return b.Fitness - a.Fitness; });
+      byId("generation").textContent = snapshot.Generation;
+      byId("message").textContent = "";
+      renderPopulation();
+      [0, 1].forEach(function(pane) {
+        // avoid refetching the svg unless the selection changed
+        var shown = byId("pane" + pane).getAttribute("data-uuid");
+        if (shown !== (selected[pane] || "") || !find(shown)) { renderPane(pane); }
+      });
+    })
+    .catch(function(err) { byId("message").textContent = err.message; });
+
+  fetch("metrics")
+    .then(function(response) { return response.ok ? response.json() : []; })
+    .then(renderChart)
+    .catch(function() {});
+}
+
+refresh();
+setInterval(function() {
+  if (byId("autorefresh").checked) { refresh(); }
+}, 5000);
+</script>
+</body>
+</html>
+`
@@ -0,0 +1,25 @@
+package neurvolve
+
+import (
+	"github.com/couchbaselabs/go.assert"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestDashboardHandler(t *testing.T) {
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest("GET", "/dashboard", nil)
+	DashboardHandler(w, r)
+
+	assert.Equals(t, w.Code, 200)
+	assert.True(t, strings.HasPrefix(w.Header().Get("Content-Type"), "text/html"))
+
+	// the page must be self-contained
+	body := w.Body.String()
+	assert.True(t, strings.Contains(body, "<script>"))
+	assert.False(t, strings.Contains(body, "<script src"))
+	assert.False(t, strings.Contains(body, "<link"))
+
+}
@@ -0,0 +1,357 @@
+package neurvolve
+
+import (
+	"encoding/csv"
+	"fmt"
+	"github.com/couchbaselabs/logg"
+	ng "github.com/maxxk/neurgo"
+	"io"
+	"math"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type Normalization string
+
+const (
+	NORMALIZE_NONE Normalization = ""
+
+	// Scale each input column to [0, 1]
+	NORMALIZE_MIN_MAX Normalization = "min_max"
+
+	// Scale each input column to zero mean and unit standard deviation
+	NORMALIZE_Z_SCORE Normalization = "z_score"
+)
+
+type CsvOptions struct {
+
+	// Defaults to a tab for .tsv files and a comma otherwise
+	Delimiter rune
+
+	// If true, the first row contains the column names
+	Header bool
+
+	// Columns to use as inputs, by name if there is a header, or by zero
+	// based index.  Defaults to all columns which are not targets.
+	InputColumns []string
+
+	// Columns to use as expected outputs, by name or index
+	TargetColumns []string
+
+	// Applied to the input columns.  The Samples of the dataset are
+	// normalized with the statistics of the whole file, while Split uses
+	// those of the training samples only.
+	Normalization Normalization
+
+	// If true, each target column holds a category, which is encoded as
+	// a vector with a 1 for the category and 0 for all others
+	OneHotTargets bool
+}
+
+// Training samples read from a file.  Each sample has a single input
+// vector and a single expected output vector, so it suits cortexes with
+// one sensor and one actuator.
+type Dataset struct {
+	Samples []*ng.TrainingSample
+
+	// Name of each element of the input and output vectors.  One-hot
+	// encoded outputs are named column=category.
+	InputNames  []string
+	OutputNames []string
+
+	// The samples before normalization, for Split
+	unnormalized  []*ng.TrainingSample
+	normalization Normalization
+}
+
+// Load a dataset from a .csv or .tsv file
+func LoadCsvDataset(filename string, options CsvOptions) (*Dataset, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	if options.Delimiter == 0 && strings.ToLower(filepath.Ext(filename)) == ".tsv" {
+		options.Delimiter = '\t'
+	}
+	return ReadCsvDataset(file, options)
+}
+
+func ReadCsvDataset(r io.Reader, options CsvOptions) (*Dataset, error) {
+
+	switch options.Normalization {
+	case NORMALIZE_NONE, NORMALIZE_MIN_MAX, NORMALIZE_Z_SCORE:
+	default:
+		return nil, fmt.Errorf("Unknown normalization: %q", options.Normalization)
+	}
+
+	reader := csv.NewReader(r)
+	if options.Delimiter != 0 {
+		reader.Comma = options.Delimiter
+	}
+	reader.TrimLeadingSpace = true
+	rows, err := reader.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return nil, fmt.Errorf("No rows in dataset")
+	}
+
+	var header []string
+	if options.Header {
+		header = rows[0]
+		rows = rows[1:]
+	} else {
+		for i := range rows[0] {
+			header = append(header, strconv.Itoa(i))
+		}
+	}
+
+	targetColumns, err := columnIndexes(header, options.TargetColumns)
+	if err != nil {
+		return nil, err
+	}
+	if len(targetColumns) == 0 {
+		return nil, fmt.Errorf("No target columns given")
+	}
+	inputColumns, err := columnIndexes(header, options.InputColumns)
+	if err != nil {
+		return nil, err
+	}
+	if len(options.InputColumns) == 0 {
+		inputColumns = otherColumns(len(header), targetColumns)
+	}
+
+	dataset := &Dataset{}
+	for _, column := range inputColumns {
+		dataset.InputNames = append(dataset.InputNames, header[column])
+	}
+
+	inputs := make([][]float64, len(rows))
+	for i, row := range rows {
+		inputs[i], err = parseColumns(row, inputColumns, i)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var outputs [][]float64
+	if options.OneHotTargets {
+		outputs, dataset.OutputNames = oneHotEncode(rows, targetColumns, header)
+	} else {
+		outputs = make([][]float64, len(rows))
+		for i, row := range rows {
+			outputs[i], err = parseColumns(row, targetColumns, i)
+			if err != nil {
+				return nil, err
+			}
+		}
+		for _, column := range targetColumns {
+			dataset.OutputNames = append(dataset.OutputNames, header[column])
+		}
+	}
+
+	for i := range rows {
+		dataset.unnormalized = append(dataset.unnormalized, &ng.TrainingSample{
+			SampleInputs:    [][]float64{inputs[i]},
+			ExpectedOutputs: [][]float64{outputs[i]},
+		})
+	}
+	dataset.normalization = options.Normalization
+	dataset.Samples = normalizeSamples(dataset.unnormalized, dataset.unnormalized, options.Normalization)
+	return dataset, nil
+
+}
+
+// Randomly split the samples into a training set and a holdout set
+// which holds the given fraction of them
+func SplitSamples(samples []*ng.TrainingSample, validationFraction float64) (training, validation []*ng.TrainingSample) {
+
+	if validationFraction < 0 || validationFraction >= 1 {
+		logg.LogPanic("Validation fraction must be in [0, 1), got %v", validationFraction)
+	}
+
+	numValidation := int(math.Floor(validationFraction*float64(len(samples)) + 0.5))
+	for i, j := range rand.Perm(len(samples)) {
+		if i < numValidation {
+			validation = append(validation, samples[j])
+		} else {
+			training = append(training, samples[j])
+		}
+	}
+	return
+
+}
+
+// Split the dataset, see SplitSamples.  The inputs of both sets are
+// normalized with the statistics of the training set, so that nothing
+// about the validation samples leaks into training.
+func (dataset *Dataset) Split(validationFraction float64) (training, validation []*ng.TrainingSample) {
+	if dataset.unnormalized == nil {
+		return SplitSamples(dataset.Samples, validationFraction)
+	}
+	training, validation = SplitSamples(dataset.unnormalized, validationFraction)
+	normalizedTraining := normalizeSamples(training, training, dataset.normalization)
+	normalizedValidation := normalizeSamples(validation, training, dataset.normalization)
+	return normalizedTraining, normalizedValidation
+}
+
+func columnIndexes(header []string, columns []string) ([]int, error) {
+	indexes := make([]int, 0)
+	for _, column := range columns {
+		index := -1
+		for i, name := range header {
+			if name == column {
+				index = i
+				break
+			}
+		}
+		if index == -1 {
+			if parsed, err := strconv.Atoi(column); err == nil && parsed >= 0 && parsed < len(header) {
+				index = parsed
+			}
+		}
+		if index == -1 {
+			return nil, fmt.Errorf("Unknown column: %q", column)
+		}
+		indexes = append(indexes, index)
+	}
+	return indexes, nil
+}
+
+func otherColumns(numColumns int, excluded []int) []int {
+	isExcluded := make(map[int]bool)
+	for _, column := range excluded {
+		isExcluded[column] = true
+	}
+	others := make([]int, 0)
+	for i := 0; i < numColumns; i++ {
+		if !isExcluded[i] {
+			others = append(others, i)
+		}
+	}
+	return others
+}
+
+func parseColumns(row []string, columns []int, rowIndex int) ([]float64, error) {
+	values := make([]float64, len(columns))
+	for i, column := range columns {
+		if column >= len(row) {
+			return nil, fmt.Errorf("Row %d has no column %d", rowIndex, column)
+		}
+		value, err := strconv.ParseFloat(strings.TrimSpace(row[column]), 64)
+		if err != nil {
+			return nil, fmt.Errorf("Row %d column %d: %v", rowIndex, column, err)
+		}
+		values[i] = value
+	}
+	return values, nil
+}
+
+// Copies of the samples with their inputs normalized using the
+// statistics of the inputs of statsSamples.  Constant columns become 0.
+func normalizeSamples(samples, statsSamples []*ng.TrainingSample, normalization Normalization) []*ng.TrainingSample {
+
+	offsets, scales := normalizationStats(sampleInputs(statsSamples), normalization)
+
+	normalized := make([]*ng.TrainingSample, len(samples))
+	for i, sample := range samples {
+		inputs := append([]float64{}, sample.SampleInputs[0]...)
+		if offsets != nil {
+			for column := range inputs {
+				if scales[column] == 0 {
+					inputs[column] = 0
+				} else {
+					inputs[column] = (inputs[column] - offsets[column]) / scales[column]
+				}
+			}
+		}
+		normalized[i] = &ng.TrainingSample{
+			SampleInputs:    [][]float64{inputs},
+			ExpectedOutputs: sample.ExpectedOutputs,
+		}
+	}
+	return normalized
+
+}
+
+// The offset and scale of each column of the vectors, or nil if they
+// aren't normalized
+func normalizationStats(vectors [][]float64, normalization Normalization) (offsets, scales []float64) {
+
+	if normalization == NORMALIZE_NONE || len(vectors) == 0 {
+		return nil, nil
+	}
+
+	offsets = make([]float64, len(vectors[0]))
+	scales = make([]float64, len(vectors[0]))
+	for column := range vectors[0] {
+
+		values := make([]float64, len(vectors))
+		for i, vector := range vectors {
+			values[i] = vector[column]
+		}
+
+		switch normalization {
+		case NORMALIZE_MIN_MAX:
+			min, max := values[0], values[0]
+			for _, value := range values {
+				min = math.Min(min, value)
+				max = math.Max(max, value)
+			}
+			offsets[column], scales[column] = min, max-min
+		case NORMALIZE_Z_SCORE:
+			offsets[column], scales[column] = AggregateMean(values), standardDeviation(values)
+		default:
+			logg.LogPanic("Unknown normalization: %q", normalization)
+		}
+
+	}
+	return
+
+}
+
+func sampleInputs(samples []*ng.TrainingSample) [][]float64 {
+	inputs := make([][]float64, len(samples))
+	for i, sample := range samples {
+		inputs[i] = sample.SampleInputs[0]
+	}
+	return inputs
+}
+
+// Encode each target column as one element per category, with the
+// categories in sorted order
+func oneHotEncode(rows [][]string, columns []int, header []string) (vectors [][]float64, names []string) {
+
+	vectors = make([][]float64, len(rows))
+	for _, column := range columns {
+
+		categorySet := make(map[string]bool)
+		for _, row := range rows {
+			categorySet[strings.TrimSpace(row[column])] = true
+		}
+		categories := make([]string, 0, len(categorySet))
+		for category := range categorySet {
+			categories = append(categories, category)
+		}
+		sort.Strings(categories)
+
+		for _, category := range categories {
+			names = append(names, fmt.Sprintf("%s=%s", header[column], category))
+		}
+		for i, row := range rows {
+			encoded := make([]float64, len(categories))
+			category := strings.TrimSpace(row[column])
+			encoded[sort.SearchStrings(categories, category)] = 1
+			vectors[i] = append(vectors[i], encoded...)
+		}
+
+	}
+	return
+
+}
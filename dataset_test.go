@@ -0,0 +1,156 @@
+package neurvolve
+
+import (
+	"fmt"
+	"github.com/couchbaselabs/go.assert"
+	ng "github.com/maxxk/neurgo"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const irisCsv = `sepal_length,sepal_width,petal_length,species
+5.1,3.5,1.4,setosa
+7.0,3.2,4.7,versicolor
+6.3,3.3,6.0,virginica
+4.9,3.0,1.4,setosa
+`
+
+func TestReadCsvDataset(t *testing.T) {
+
+	options := CsvOptions{
+		Header:        true,
+		InputColumns:  []string{"sepal_length", "petal_length"},
+		TargetColumns: []string{"species"},
+		Normalization: NORMALIZE_MIN_MAX,
+		OneHotTargets: true,
+	}
+	dataset, err := ReadCsvDataset(strings.NewReader(irisCsv), options)
+	assert.True(t, err == nil)
+
+	assert.Equals(t, len(dataset.Samples), 4)
+	assert.Equals(t, strings.Join(dataset.InputNames, ","), "sepal_length,petal_length")
+	assert.Equals(t, strings.Join(dataset.OutputNames, ","), "species=setosa,species=versicolor,species=virginica")
+
+	// sepal length ranges from 4.9 to 7.0, petal length from 1.4 to 6.0
+	sample := dataset.Samples[0]
+	assert.True(t, math.Abs(sample.SampleInputs[0][0]-0.2/2.1) < 1e-9)
+	assert.Equals(t, sample.SampleInputs[0][1], 0.0)
+	assert.Equals(t, dataset.Samples[1].SampleInputs[0][0], 1.0)
+
+	assert.Equals(t, len(sample.ExpectedOutputs[0]), 3)
+	assert.Equals(t, sample.ExpectedOutputs[0][0], 1.0)
+	assert.Equals(t, dataset.Samples[2].ExpectedOutputs[0][2], 1.0)
+
+	options.TargetColumns = []string{"no_such_column"}
+	_, err = ReadCsvDataset(strings.NewReader(irisCsv), options)
+	assert.True(t, err != nil)
+
+}
+
+func TestLoadCsvDatasetTsv(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "neurvolve-dataset")
+	assert.True(t, err == nil)
+	defer os.RemoveAll(dir)
+
+	filename := filepath.Join(dir, "xnor.tsv")
+	contents := "-1\t-1\t1\n-1\t1\t-1\n1\t-1\t-1\n1\t1\t1\n"
+	assert.True(t, ioutil.WriteFile(filename, []byte(contents), 0644) == nil)
+
+	options := CsvOptions{
+		TargetColumns: []string{"2"},
+		Normalization: NORMALIZE_Z_SCORE,
+	}
+	dataset, err := LoadCsvDataset(filename, options)
+	assert.True(t, err == nil)
+
+	// inputs already have zero mean and unit standard deviation
+	assert.Equals(t, len(dataset.Samples), 4)
+	assert.Equals(t, dataset.Samples[1].SampleInputs[0][1], 1.0)
+	assert.Equals(t, dataset.Samples[1].ExpectedOutputs[0][0], -1.0)
+
+}
+
+func TestSplitSamples(t *testing.T) {
+
+	samples := make([]*ng.TrainingSample, 10)
+	for i := range samples {
+		samples[i] = &ng.TrainingSample{SampleInputs: [][]float64{{float64(i)}}}
+	}
+
+	training, validation := SplitSamples(samples, 0.3)
+	assert.Equals(t, len(training), 7)
+	assert.Equals(t, len(validation), 3)
+
+	seen := make(map[*ng.TrainingSample]bool)
+	for _, sample := range append(training, validation...) {
+		assert.False(t, seen[sample])
+		seen[sample] = true
+	}
+
+}
+
+func TestDatasetSplitNormalizesWithTrainingStats(t *testing.T) {
+
+	// the target is a copy of the input, to recover the raw values
+	rows := make([]string, 0)
+	for i := 0; i < 10; i++ {
+		rows = append(rows, fmt.Sprintf("%d,%d", i*i, i*i))
+	}
+	options := CsvOptions{
+		InputColumns:  []string{"0"},
+		TargetColumns: []string{"1"},
+		Normalization: NORMALIZE_MIN_MAX,
+	}
+	dataset, err := ReadCsvDataset(strings.NewReader(strings.Join(rows, "\n")), options)
+	assert.True(t, err == nil)
+
+	training, validation := dataset.Split(0.3)
+	assert.Equals(t, len(training), 7)
+	assert.Equals(t, len(validation), 3)
+
+	min, max := math.Inf(1), math.Inf(-1)
+	for _, sample := range training {
+		min = math.Min(min, sample.ExpectedOutputs[0][0])
+		max = math.Max(max, sample.ExpectedOutputs[0][0])
+	}
+	for _, sample := range append(training, validation...) {
+		expected := (sample.ExpectedOutputs[0][0] - min) / (max - min)
+		assert.True(t, math.Abs(sample.SampleInputs[0][0]-expected) < 1e-9)
+	}
+
+	// the samples of the dataset itself are still normalized over all rows
+	assert.Equals(t, dataset.Samples[9].SampleInputs[0][0], 1.0)
+
+}
+
+func TestPopulationTrainerValidation(t *testing.T) {
+
+	generations := make([]int, 0)
+	pt := &PopulationTrainer{
+		FitnessThreshold: 2.0,
+		MaxGenerations:   2,
+		CortexMutator:    NoOpMutator,
+		Metrics:          NewMetricsHistory(10),
+		ValidationScape:  ConstantScape{fitness: 0.25},
+		ValidationHook: func(generation int, fittest EvaluatedCortex, validationFitness float64) {
+			assert.Equals(t, fittest.Fitness, 1.0)
+			assert.Equals(t, validationFitness, 0.25)
+			generations = append(generations, generation)
+		},
+	}
+
+	population := []*ng.Cortex{SingleNeuronCortex("cortex1"), SingleNeuronCortex("cortex2")}
+	pt.Train(population, ConstantScape{fitness: 1.0}, NewNullRecorder())
+
+	assert.Equals(t, len(generations), 2)
+	stats, ok := pt.Metrics.Latest()
+	assert.True(t, ok)
+	assert.Equals(t, stats.BestFitness, 1.0)
+	assert.Equals(t, *stats.ValidationFitness, 0.25)
+
+}
@@ -12,6 +12,9 @@ type EvaluatedCortex struct {
 	Fitness             float64
 	ParentId            string
 	CreatedInGeneration int
+
+	// Self-adaptive mutation parameters, only used with an AdaptiveCortexMutator
+	Strategy *MutationStrategy
 }
 
 type EvaluatedCortexes []EvaluatedCortex
@@ -38,11 +41,11 @@ func (evaldCortexes EvaluatedCortexes) Uuids() [][]string {
 	return uuids
 }
 
-func (evaldCortexes EvaluatedCortexes) Find(uuid string) EvaluatedCortex {
+func (evaldCortexes EvaluatedCortexes) Find(uuid string) (evaldCortex EvaluatedCortex, found bool) {
 	for _, evaldCortex := range evaldCortexes {
 		if evaldCortex.Cortex.NodeId.UUID == uuid {
-			return evaldCortex
+			return evaldCortex, true
 		}
 	}
-	return EvaluatedCortex{}
+	return EvaluatedCortex{}, false
 }
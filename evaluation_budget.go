@@ -0,0 +1,171 @@
+package neurvolve
+
+import (
+	ng "github.com/maxxk/neurgo"
+	"sync"
+	"time"
+)
+
+// Counts the calls to Fitness and FitnessAgainst made by a trainer, and
+// stops it once MaxEvaluations or MaxDuration are used up.  Unlike
+// generations or hill climbing attempts, evaluations mean the same thing
+// for every trainer, so a budget makes algorithms comparable.
+//
+// Trainers only check the budget between evaluations (the population
+// trainer between generations), so they may overshoot it slightly.
+type EvaluationBudget struct {
+
+	// Maximum number of evaluations, or 0 for no limit
+	MaxEvaluations int
+
+	// Maximum wall time from the first evaluation, or 0 for no limit
+	MaxDuration time.Duration
+
+	mutex                 sync.Mutex
+	evaluations           int
+	evaluationsToSolution int
+	started               time.Time
+}
+
+// What a trainer used of its budget
+type BudgetUsage struct {
+	Evaluations int
+	Duration    time.Duration
+	Exhausted   bool
+
+	// Whether the trainer reached its fitness threshold, and after how
+	// many evaluations
+	Solved                bool
+	EvaluationsToSolution int
+}
+
+// Scape which counts its evaluations against a budget
+type budgetScape struct {
+	scape  Scape
+	budget *EvaluationBudget
+}
+
+// Wrap the scape so that its evaluations are counted against the budget.
+// A nil budget, or a scape which already counts against the budget, eg
+// when a trainer passes it on to a hill climber, is returned as is.
+func (budget *EvaluationBudget) Scape(scape Scape) Scape {
+	if budget == nil || budget.counts(scape) {
+		return scape
+	}
+	return &budgetScape{scape: scape, budget: budget}
+}
+
+// Whether the scape, or one it wraps, counts against the budget
+func (budget *EvaluationBudget) counts(scape Scape) bool {
+	for {
+		counted, ok := scape.(*budgetScape)
+		if !ok {
+			return false
+		}
+		if counted.budget == budget {
+			return true
+		}
+		scape = counted.scape
+	}
+}
+
+// The number of evaluations so far.  A nil budget hasn't counted any.
+func (budget *EvaluationBudget) Evaluations() int {
+	if budget == nil {
+		return 0
+	}
+	budget.mutex.Lock()
+	defer budget.mutex.Unlock()
+	return budget.evaluations
+}
+
+// Whether the evaluations or wall time are used up.  A nil budget never is.
+func (budget *EvaluationBudget) Exhausted() bool {
+	if budget == nil {
+		return false
+	}
+	budget.mutex.Lock()
+	defer budget.mutex.Unlock()
+	return budget.exhausted()
+}
+
+// Whether the budget limits the evaluations or wall time, and so can stop
+// a trainer by itself.  A nil budget doesn't.
+func (budget *EvaluationBudget) limited() bool {
+	return budget != nil && (budget.MaxEvaluations > 0 || budget.MaxDuration > 0)
+}
+
+// How much of the budget was used.  A nil budget reports no usage.
+func (budget *EvaluationBudget) Usage() BudgetUsage {
+	if budget == nil {
+		return BudgetUsage{}
+	}
+	budget.mutex.Lock()
+	defer budget.mutex.Unlock()
+	return BudgetUsage{
+		Evaluations:           budget.evaluations,
+		Duration:              budget.elapsed(),
+		Exhausted:             budget.exhausted(),
+		Solved:                budget.evaluationsToSolution > 0,
+		EvaluationsToSolution: budget.evaluationsToSolution,
+	}
+}
+
+// Called by a trainer when it reaches its fitness threshold, to record
+// the evaluations used so far
+func (budget *EvaluationBudget) recordSolution() {
+	if budget == nil {
+		return
+	}
+	budget.mutex.Lock()
+	defer budget.mutex.Unlock()
+	if budget.evaluationsToSolution == 0 {
+		budget.evaluationsToSolution = budget.evaluations
+	}
+}
+
+func (budget *EvaluationBudget) count() {
+	budget.mutex.Lock()
+	defer budget.mutex.Unlock()
+	if budget.evaluations == 0 {
+		budget.started = time.Now()
+	}
+	budget.evaluations += 1
+}
+
+func (budget *EvaluationBudget) exhausted() bool {
+	if budget.MaxEvaluations > 0 && budget.evaluations >= budget.MaxEvaluations {
+		return true
+	}
+	return budget.MaxDuration > 0 && budget.elapsed() >= budget.MaxDuration
+}
+
+func (budget *EvaluationBudget) elapsed() time.Duration {
+	if budget.evaluations == 0 {
+		return 0
+	}
+	return time.Since(budget.started)
+}
+
+func (scape *budgetScape) Fitness(cortex *ng.Cortex) float64 {
+	scape.budget.count()
+	return scape.scape.Fitness(cortex)
+}
+
+func (scape *budgetScape) FitnessAgainst(cortex *ng.Cortex, opponent *ng.Cortex) float64 {
+	scape.budget.count()
+	return scape.scape.FitnessAgainst(cortex, opponent)
+}
+
+func (scape *budgetScape) StartGeneration(generation int, bestFitness float64) {
+	startGeneration([]Scape{scape.scape}, generation, bestFitness)
+}
+
+func (scape *budgetScape) Sampled() bool {
+	return isSampled(scape.scape)
+}
+
+func (scape *budgetScape) FullFitness(cortex *ng.Cortex) float64 {
+	scape.budget.count()
+	return fullFitness(scape.scape, cortex)
+}
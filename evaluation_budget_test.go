@@ -0,0 +1,119 @@
+package neurvolve
+
+import (
+	"github.com/couchbaselabs/go.assert"
+	ng "github.com/maxxk/neurgo"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestEvaluationBudget(t *testing.T) {
+
+	var noBudget *EvaluationBudget
+	constantScape := ConstantScape{1}
+	assert.Equals(t, noBudget.Scape(constantScape), Scape(constantScape))
+	assert.False(t, noBudget.Exhausted())
+	assert.Equals(t, noBudget.Evaluations(), 0)
+	assert.Equals(t, noBudget.Usage(), BudgetUsage{})
+
+	budget := &EvaluationBudget{MaxEvaluations: 2}
+	scape := budget.Scape(constantScape)
+	assert.Equals(t, budget.Scape(scape), scape)
+
+	cortex := SingleNeuronCortex("cortex")
+	scape.Fitness(cortex)
+	assert.False(t, budget.Exhausted())
+	budget.recordSolution()
+	scape.FitnessAgainst(cortex, SingleNeuronCortex("opponent"))
+	assert.True(t, budget.Exhausted())
+
+	usage := budget.Usage()
+	assert.Equals(t, usage.Evaluations, 2)
+	assert.True(t, usage.Exhausted)
+	assert.True(t, usage.Solved)
+	assert.Equals(t, usage.EvaluationsToSolution, 1)
+
+	budget = &EvaluationBudget{MaxDuration: time.Nanosecond}
+	budget.Scape(constantScape).Fitness(cortex)
+	time.Sleep(time.Millisecond)
+	assert.True(t, budget.Exhausted())
+
+}
+
+func TestPopulationTrainerBudget(t *testing.T) {
+
+	budget := &EvaluationBudget{MaxEvaluations: 5}
+	pt := &PopulationTrainer{
+		FitnessThreshold: math.Inf(1),
+		CortexMutator:    NoOpMutator,
+		Budget:           budget,
+	}
+	population := []*ng.Cortex{SingleNeuronCortex("cortex1"), SingleNeuronCortex("cortex2")}
+	scape := &GenerationRecordingScape{ConstantScape: ConstantScape{1}}
+	_, succeeded := pt.Train(population, scape, NewNullRecorder())
+
+	// stops at the first generation boundary after the budget is used up
+	assert.False(t, succeeded)
+	assert.Equals(t, budget.Evaluations(), 6)
+	assert.False(t, budget.Usage().Solved)
+
+	// generations are still passed on through the budget
+	assert.Equals(t, len(scape.generations), 3)
+
+	// a budget without limits leaves MaxGenerations in charge
+	budget = &EvaluationBudget{}
+	pt.Budget = budget
+	pt.MaxGenerations = 2
+	pt.Train(population, ConstantScape{1}, NewNullRecorder())
+	assert.Equals(t, budget.Evaluations(), 4)
+	pt.MaxGenerations = 0
+	pt.Train(population, ConstantScape{1}, NewNullRecorder())
+	assert.Equals(t, budget.Evaluations(), 4)
+
+}
+
+func TestStochasticHillClimberBudget(t *testing.T) {
+
+	budget := &EvaluationBudget{MaxEvaluations: 10}
+	shc := &StochasticHillClimber{
+		FitnessThreshold:           math.Inf(1),
+		MaxIterationsBeforeRestart: 3,
+		WeightSaturationRange:      []float64{-10 * math.Pi, 10 * math.Pi},
+		Budget:                     budget,
+	}
+	_, _, succeeded := shc.Train(BasicCortex(), ConstantScape{1})
+	assert.False(t, succeeded)
+	assert.Equals(t, budget.Evaluations(), 10)
+
+	budget = &EvaluationBudget{}
+	shc.FitnessThreshold = 0.5
+	shc.Budget = budget
+	_, _, succeeded = shc.Train(BasicCortex(), ConstantScape{1})
+	assert.True(t, succeeded)
+	assert.Equals(t, budget.Usage().EvaluationsToSolution, 1)
+
+}
+
+func TestTopologyMutatingTrainerBudget(t *testing.T) {
+
+	budget := &EvaluationBudget{MaxEvaluations: 10}
+	tmt := &TopologyMutatingTrainer{
+		MaxIterationsBeforeRestart: 5,
+		Mutators:                   []CortexMutator{NoOpMutator},
+		StochasticHillClimber: &StochasticHillClimber{
+			FitnessThreshold:           math.Inf(1),
+			MaxIterationsBeforeRestart: 3,
+			WeightSaturationRange:      []float64{-10 * math.Pi, 10 * math.Pi},
+		},
+		Budget: budget,
+	}
+
+	// the evaluations of the hill climber are only counted once
+	scape := &CountingScape{}
+	result := tmt.TrainFrom(BasicCortex, scape)
+	assert.Equals(t, result.StopReason, STOP_BUDGET_EXHAUSTED)
+	assert.Equals(t, budget.Evaluations(), scape.numEvaluations)
+	assert.Equals(t, result.Evaluations, scape.numEvaluations)
+
+}
@@ -0,0 +1,98 @@
+package neurvolve
+
+import (
+	ng "github.com/maxxk/neurgo"
+	"sync"
+)
+
+const DEFAULT_EVENT_BUFFER_SIZE = 100
+
+const (
+	EVENT_GENERATION = "generation"
+	EVENT_MATCH      = "match"
+)
+
+type TrainingEvent struct {
+	Name string
+	Data interface{}
+}
+
+// Published after each generation's fitness has been computed
+type GenerationEvent struct {
+	Stats           GenerationStats
+	ChampionUuid    string
+	ChampionFitness float64
+}
+
+// Published after two cortexes face off
+type MatchEvent struct {
+	Generation   int
+	CortexUuid   string
+	OpponentUuid string
+	Score        float64
+}
+
+// Fans training events out to any number of subscribers.  Publishing never
+// blocks the trainer: events are dropped for subscribers whose buffer is full.
+type EventBroadcaster struct {
+	mutex       sync.Mutex
+	subscribers map[chan TrainingEvent]bool
+	bufferSize  int
+}
+
+func NewEventBroadcaster(bufferSize int) *EventBroadcaster {
+	if bufferSize <= 0 {
+		bufferSize = DEFAULT_EVENT_BUFFER_SIZE
+	}
+	return &EventBroadcaster{
+		subscribers: make(map[chan TrainingEvent]bool),
+		bufferSize:  bufferSize,
+	}
+}
+
+func (broadcaster *EventBroadcaster) Subscribe() chan TrainingEvent {
+	broadcaster.mutex.Lock()
+	defer broadcaster.mutex.Unlock()
+	events := make(chan TrainingEvent, broadcaster.bufferSize)
+	broadcaster.subscribers[events] = true
+	return events
+}
+
+func (broadcaster *EventBroadcaster) Unsubscribe(events chan TrainingEvent) {
+	broadcaster.mutex.Lock()
+	defer broadcaster.mutex.Unlock()
+	if broadcaster.subscribers[events] {
+		delete(broadcaster.subscribers, events)
+		close(events)
+	}
+}
+
+func (broadcaster *EventBroadcaster) PublishGeneration(stats GenerationStats, champion EvaluatedCortex) {
+	event := GenerationEvent{
+		Stats:           stats,
+		ChampionUuid:    champion.Cortex.NodeId.UUID,
+		ChampionFitness: champion.Fitness,
+	}
+	broadcaster.publish(TrainingEvent{Name: EVENT_GENERATION, Data: event})
+}
+
+func (broadcaster *EventBroadcaster) PublishMatch(generation int, score float64, cortex *ng.Cortex, opponent *ng.Cortex) {
+	event := MatchEvent{
+		Generation:   generation,
+		CortexUuid:   cortex.NodeId.UUID,
+		OpponentUuid: opponent.NodeId.UUID,
+		Score:        score,
+	}
+	broadcaster.publish(TrainingEvent{Name: EVENT_MATCH, Data: event})
+}
+
+func (broadcaster *EventBroadcaster) publish(event TrainingEvent) {
+	broadcaster.mutex.Lock()
+	defer broadcaster.mutex.Unlock()
+	for events := range broadcaster.subscribers {
+		select {
+		case events <- event:
+		default:
+		}
+	}
+}
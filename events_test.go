@@ -0,0 +1,49 @@
+package neurvolve
+
+import (
+	"github.com/couchbaselabs/go.assert"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestEventBroadcaster(t *testing.T) {
+
+	broadcaster := NewEventBroadcaster(1)
+	events := broadcaster.Subscribe()
+
+	champion := EvaluatedCortex{Cortex: SingleNeuronCortex("champion"), Fitness: 42}
+	broadcaster.PublishGeneration(GenerationStats{Generation: 3}, champion)
+
+	// buffer is full, so this is dropped rather than blocking
+	broadcaster.PublishMatch(3, 1.0, champion.Cortex, champion.Cortex)
+
+	event := <-events
+	assert.Equals(t, event.Name, EVENT_GENERATION)
+	generationEvent := event.Data.(GenerationEvent)
+	assert.Equals(t, generationEvent.ChampionUuid, "champion")
+	assert.Equals(t, generationEvent.ChampionFitness, 42.0)
+	assert.Equals(t, generationEvent.Stats.Generation, 3)
+
+	broadcaster.Unsubscribe(events)
+	_, ok := <-events
+	assert.False(t, ok)
+
+	// publishing without subscribers is fine
+	broadcaster.PublishMatch(3, 1.0, champion.Cortex, champion.Cortex)
+
+}
+
+func TestWriteServerSentEvent(t *testing.T) {
+
+	w := httptest.NewRecorder()
+	event := TrainingEvent{
+		Name: EVENT_MATCH,
+		Data: MatchEvent{Generation: 1, CortexUuid: "a", OpponentUuid: "b", Score: 0.5},
+	}
+	err := writeServerSentEvent(w, event)
+	assert.True(t, err == nil)
+
+	expected := "event: match\ndata: {\"Generation\":1,\"CortexUuid\":\"a\",\"OpponentUuid\":\"b\",\"Score\":0.5}\n\n"
+	assert.Equals(t, w.Body.String(), expected)
+
+}
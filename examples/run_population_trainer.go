@@ -6,6 +6,7 @@ import (
 	ng "github.com/maxxk/neurgo"
 	nv "github.com/maxxk/neurvolve"
 	"math"
+	"net/http"
 	"time"
 )
 
@@ -19,10 +20,10 @@ func RunPopulationTrainerLoop(maxIterations int) bool {
 		// CortexMutator: nv.MutateWeights,
 		// CortexMutator: RandomNeuronMutator,
 		// CortexMutator:       nv.TopologyOrWeightMutator,
-		NumOpponents:        5,
-		SnapshotRequestChan: make(chan chan nv.EvaluatedCortexes),
+		NumOpponents: 5,
+		Snapshots:    nv.NewSnapshotStore(),
 	}
-	nv.RegisterHandlers(pt)
+	nv.RegisterHandlers(http.DefaultServeMux, pt)
 
 	for i := 0; i < maxIterations; i++ {
 		succeeded := RunPopulationTrainer(pt)
@@ -108,18 +109,15 @@ func getScape() nv.Scape {
 
 func getInitialPopulation() []*ng.Cortex {
 
-	population := make([]*ng.Cortex, 0)
-
-	// create 30 of these
-	for i := 0; i < 30; i++ {
-
-		cortex := ng.XnorCortexUntrained()
-		// cortex := ng.XnorCortex()
-
-		population = append(population, cortex)
-
+	// same shape as ng.XnorCortexUntrained: two inputs, two hidden
+	// neurons and one output
+	factory := &nv.PopulationFactory{
+		Sensors:          []nv.SensorSpec{{Name: "sensor", VectorLength: 2}},
+		Actuators:        []nv.ActuatorSpec{{Name: "actuator", VectorLength: 1}},
+		Template:         nv.TEMPLATE_HIDDEN_LAYER,
+		HiddenLayerWidth: 2,
+		Activations:      []*ng.EncodableActivation{ng.EncodableSigmoid()},
 	}
-
-	return population
+	return factory.NewPopulation(30)
 
 }
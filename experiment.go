@@ -0,0 +1,355 @@
+package neurvolve
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/couchbaselabs/logg"
+	ng "github.com/maxxk/neurgo"
+	"gopkg.in/yaml.v2"
+	"io/ioutil"
+	"math/rand"
+	"net"
+	"net/http"
+	"path/filepath"
+	"time"
+)
+
+const (
+	TRAINER_POPULATION              = "population"
+	TRAINER_STOCHASTIC_HILL_CLIMBER = "stochastic_hill_climber"
+	TRAINER_TOPOLOGY_MUTATING       = "topology_mutating"
+)
+
+const DEFAULT_POPULATION_SIZE = 30
+
+// A declarative description of a training run, which refers to scapes,
+// cortexes, mutators and recorders by their name in a Registry.
+type ExperimentSpec struct {
+	Name string `json:"name" yaml:"name"`
+
+	// Seed for math/rand.  If zero, a time based seed is used, and the
+	// hill climbing trainers reseed whenever they restart.
+	Seed int64 `json:"seed" yaml:"seed"`
+
+	Trainer TrainerSpec `json:"trainer" yaml:"trainer"`
+
+	// Mutators to choose from at random when creating offspring
+	// (population trainer) or mutating topology (topology mutating
+	// trainer).  Not used by the stochastic hill climber.
+	Mutators []string `json:"mutators" yaml:"mutators"`
+
+	Scape      string         `json:"scape" yaml:"scape"`
+	Population PopulationSpec `json:"population" yaml:"population"`
+
+	// Recorder for the population trainer, defaults to "null"
+	Recorder string `json:"recorder" yaml:"recorder"`
+
+	// If non-zero, the http handlers are served on this port while
+	// the trainer runs
+	HttpPort int `json:"http_port" yaml:"http_port"`
+
+	// If set, cortexes are saved in a folder for this run under the
+	// directory, and can also be saved through the http handlers
+	ArtifactDir string `json:"artifact_dir" yaml:"artifact_dir"`
+}
+
+type TrainerSpec struct {
+	Type string `json:"type" yaml:"type"`
+
+	// Defaults to ng.FITNESS_THRESHOLD
+	FitnessThreshold float64 `json:"fitness_threshold" yaml:"fitness_threshold"`
+
+	// Population trainer
+	MaxGenerations int `json:"max_generations" yaml:"max_generations"`
+	NumOpponents   int `json:"num_opponents" yaml:"num_opponents"`
+	NumEvaluations int `json:"num_evaluations" yaml:"num_evaluations"`
+
+	// Stochastic hill climber and topology mutating trainer
+	MaxIterationsBeforeRestart int       `json:"max_iterations_before_restart" yaml:"max_iterations_before_restart"`
+	MaxAttempts                int       `json:"max_attempts" yaml:"max_attempts"`
+	WeightSaturationRange      []float64 `json:"weight_saturation_range" yaml:"weight_saturation_range"`
+
+	// The stochastic hill climber used for the memetic step
+	// of the topology mutating trainer
+	HillClimber *TrainerSpec `json:"hill_climber" yaml:"hill_climber"`
+
+	// Any trainer: stop after this many evaluations of the scape or
+	// seconds of training, if set
+	MaxEvaluations int     `json:"max_evaluations" yaml:"max_evaluations"`
+	MaxSeconds     float64 `json:"max_seconds" yaml:"max_seconds"`
+}
+
+type PopulationSpec struct {
+
+	// Name of the cortex to start from
+	Cortex string `json:"cortex" yaml:"cortex"`
+
+	// Number of copies of the cortex in the initial population of the
+	// population trainer.  Defaults to DEFAULT_POPULATION_SIZE.
+	Size int `json:"size" yaml:"size"`
+}
+
+// Load an experiment spec from a .json, .yaml or .yml file
+func LoadExperimentSpec(filename string) (*ExperimentSpec, error) {
+
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	spec := &ExperimentSpec{}
+	switch filepath.Ext(filename) {
+	case ".json":
+		err = json.Unmarshal(data, spec)
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, spec)
+	default:
+		err = fmt.Errorf("Unknown experiment file type: %v", filename)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return spec, nil
+
+}
+
+// Make sure that the spec is complete and only refers to components
+// which exist in the registry
+func (spec *ExperimentSpec) Validate(registry *Registry) error {
+
+	if _, ok := registry.Scapes[spec.Scape]; !ok {
+		return fmt.Errorf("Unknown scape: %q", spec.Scape)
+	}
+	if _, ok := registry.Cortexes[spec.Population.Cortex]; !ok {
+		return fmt.Errorf("Unknown cortex: %q", spec.Population.Cortex)
+	}
+	if _, ok := registry.Recorders[spec.recorderName()]; !ok {
+		return fmt.Errorf("Unknown recorder: %q", spec.Recorder)
+	}
+	for _, name := range spec.Mutators {
+		if _, ok := registry.Mutators[name]; !ok {
+			return fmt.Errorf("Unknown mutator: %q", name)
+		}
+	}
+
+	switch spec.Trainer.Type {
+	case TRAINER_POPULATION:
+		if len(spec.Mutators) == 0 {
+			return fmt.Errorf("Population trainer requires at least one mutator")
+		}
+		if spec.populationSize()%2 != 0 {
+			return fmt.Errorf("Population size must be even, got: %d", spec.populationSize())
+		}
+		if spec.Trainer.NumOpponents >= spec.populationSize() {
+			return fmt.Errorf("Not enough members of population for %d opponents", spec.Trainer.NumOpponents)
+		}
+	case TRAINER_STOCHASTIC_HILL_CLIMBER:
+		if len(spec.Mutators) > 0 {
+			return fmt.Errorf("Stochastic hill climber does not use mutators")
+		}
+		return spec.Trainer.validateHillClimber()
+	case TRAINER_TOPOLOGY_MUTATING:
+		if spec.Trainer.HillClimber == nil {
+			return fmt.Errorf("Topology mutating trainer requires a hill_climber")
+		}
+		if spec.Trainer.MaxIterationsBeforeRestart <= 0 || spec.Trainer.MaxAttempts <= 0 {
+			return fmt.Errorf("max_iterations_before_restart and max_attempts must be positive")
+		}
+		return spec.Trainer.HillClimber.validateHillClimber()
+	default:
+		return fmt.Errorf("Unknown trainer type: %q", spec.Trainer.Type)
+	}
+
+	return nil
+
+}
+
+// Build the components described by the spec and train
+func (spec *ExperimentSpec) Run(registry *Registry) (succeeded bool, err error) {
+
+	if err = spec.Validate(registry); err != nil {
+		return
+	}
+
+	spec.seedRandom()
+
+	scape := registry.Scapes[spec.Scape]()
+	newCortex := registry.Cortexes[spec.Population.Cortex]
+
+	artifacts, err := spec.artifactStore()
+	if err != nil {
+		return
+	}
+
+	logg.LogTo("MAIN", "Running experiment %q with %v trainer", spec.Name, spec.Trainer.Type)
+
+	budget := spec.Trainer.budget()
+
+	var trainer Trainer
+	var store PopulationStore
+	switch spec.Trainer.Type {
+	case TRAINER_POPULATION:
+
+		pt := &PopulationTrainer{
+			FitnessThreshold: spec.Trainer.fitnessThreshold(),
+			MaxGenerations:   spec.Trainer.MaxGenerations,
+			NumOpponents:     spec.Trainer.NumOpponents,
+			NumEvaluations:   spec.Trainer.NumEvaluations,
+			CortexMutator:    CombinedCortexMutator(spec.mutators(registry)...),
+			Artifacts:        artifacts,
+			Budget:           budget,
+			PopulationSize:   spec.populationSize(),
+			Recorder:         registry.Recorders[spec.recorderName()](),
+		}
+
+		if spec.HttpPort > 0 {
+			pt.Snapshots = NewSnapshotStore()
+			pt.Metrics = NewMetricsHistory(DEFAULT_METRICS_CAPACITY)
+			pt.Control = NewTrainingControl()
+			pt.Events = NewEventBroadcaster(DEFAULT_EVENT_BUFFER_SIZE)
+			store = pt
+		}
+		trainer = pt
+
+	case TRAINER_STOCHASTIC_HILL_CLIMBER:
+
+		shc := spec.Trainer.stochasticHillClimber()
+		shc.Budget = budget
+		shc.DisableReseeding = spec.Seed != 0
+		if spec.HttpPort > 0 {
+			shc.Snapshots = NewSnapshotStore()
+			store = shc
+		}
+		trainer = shc
+
+	case TRAINER_TOPOLOGY_MUTATING:
+
+		tmt := &TopologyMutatingTrainer{
+			MaxIterationsBeforeRestart: spec.Trainer.MaxIterationsBeforeRestart,
+			MaxAttempts:                spec.Trainer.MaxAttempts,
+			StochasticHillClimber:      spec.Trainer.HillClimber.stochasticHillClimber(),
+			Mutators:                   spec.mutators(registry),
+			Artifacts:                  artifacts,
+			Budget:                     budget,
+			DisableReseeding:           spec.Seed != 0,
+		}
+		tmt.StochasticHillClimber.DisableReseeding = spec.Seed != 0
+		if spec.HttpPort > 0 {
+			tmt.Snapshots = NewSnapshotStore()
+			store = tmt
+		}
+		trainer = tmt
+
+	}
+
+	if store != nil {
+		if err = spec.serveHttp(store); err != nil {
+			return
+		}
+	}
+
+	result := trainer.TrainFrom(newCortex, scape)
+	succeeded = result.Succeeded
+	logg.LogTo("MAIN", "Fittest cortex: %v, stopped (%v) after %d evaluations", result.Fitness, result.StopReason, result.Evaluations)
+	logg.LogTo("MAIN", "Experiment %q succeeded: %v", spec.Name, succeeded)
+	return
+
+}
+
+// Serve the http handlers in the background.  The port is bound right
+// away, so that an experiment doesn't run without its http api.
+func (spec *ExperimentSpec) serveHttp(store PopulationStore) error {
+	listener, err := net.Listen("tcp", fmt.Sprintf(":%d", spec.HttpPort))
+	if err != nil {
+		return fmt.Errorf("Unable to serve http on port %d: %v", spec.HttpPort, err)
+	}
+	serveMux := http.NewServeMux()
+	RegisterHandlers(serveMux, store)
+	go func() {
+		if err := http.Serve(listener, serveMux); err != nil {
+			logg.LogError(err)
+		}
+	}()
+	return nil
+}
+
+// A FileArtifactStore if an artifact dir was given, otherwise nil
+func (spec *ExperimentSpec) artifactStore() (ArtifactStore, error) {
+	if spec.ArtifactDir == "" {
+		return nil, nil
+	}
+	store, err := NewFileArtifactStore(spec.ArtifactDir, spec.Name)
+	if err != nil {
+		return nil, err
+	}
+	logg.LogTo("MAIN", "Saving artifacts to %v", store.Dir)
+	return store, nil
+}
+
+func (spec *ExperimentSpec) seedRandom() {
+	if spec.Seed == 0 {
+		ng.SeedRandom()
+	} else {
+		rand.Seed(spec.Seed)
+	}
+}
+
+func (spec *ExperimentSpec) mutators(registry *Registry) []CortexMutator {
+	mutators := make([]CortexMutator, 0)
+	for _, name := range spec.Mutators {
+		mutators = append(mutators, registry.Mutators[name])
+	}
+	return mutators
+}
+
+func (spec *ExperimentSpec) populationSize() int {
+	if spec.Population.Size == 0 {
+		return DEFAULT_POPULATION_SIZE
+	}
+	return spec.Population.Size
+}
+
+func (spec *ExperimentSpec) recorderName() string {
+	if spec.Recorder == "" {
+		return "null"
+	}
+	return spec.Recorder
+}
+
+func (trainerSpec *TrainerSpec) fitnessThreshold() float64 {
+	if trainerSpec.FitnessThreshold == 0 {
+		return ng.FITNESS_THRESHOLD
+	}
+	return trainerSpec.FitnessThreshold
+}
+
+// An EvaluationBudget if a maximum number of evaluations or seconds
+// was given, otherwise nil
+func (trainerSpec *TrainerSpec) budget() *EvaluationBudget {
+	if trainerSpec.MaxEvaluations <= 0 && trainerSpec.MaxSeconds <= 0 {
+		return nil
+	}
+	return &EvaluationBudget{
+		MaxEvaluations: trainerSpec.MaxEvaluations,
+		MaxDuration:    time.Duration(trainerSpec.MaxSeconds * float64(time.Second)),
+	}
+}
+
+func (trainerSpec *TrainerSpec) validateHillClimber() error {
+	if len(trainerSpec.WeightSaturationRange) != 2 {
+		return fmt.Errorf("weight_saturation_range must have two elements")
+	}
+	if trainerSpec.MaxIterationsBeforeRestart <= 0 || trainerSpec.MaxAttempts <= 0 {
+		return fmt.Errorf("max_iterations_before_restart and max_attempts must be positive")
+	}
+	return nil
+}
+
+func (trainerSpec *TrainerSpec) stochasticHillClimber() *StochasticHillClimber {
+	return &StochasticHillClimber{
+		FitnessThreshold:           trainerSpec.fitnessThreshold(),
+		MaxIterationsBeforeRestart: trainerSpec.MaxIterationsBeforeRestart,
+		MaxAttempts:                trainerSpec.MaxAttempts,
+		WeightSaturationRange:      trainerSpec.WeightSaturationRange,
+	}
+}
@@ -0,0 +1,93 @@
+package neurvolve
+
+import (
+	"github.com/couchbaselabs/go.assert"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadExperimentSpec(t *testing.T) {
+
+	spec, err := LoadExperimentSpec("examples/experiments/xnor_population.json")
+	assert.True(t, err == nil)
+	assert.Equals(t, spec.Trainer.Type, TRAINER_POPULATION)
+	assert.Equals(t, spec.Population.Size, 30)
+	assert.Equals(t, spec.Mutators[0], "mutate_all_weights_bell")
+
+	err = spec.Validate(DefaultRegistry())
+	assert.True(t, err == nil)
+
+}
+
+func TestLoadExperimentSpecUnknownExtension(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "neurvolve")
+	assert.True(t, err == nil)
+	defer os.RemoveAll(dir)
+
+	filename := filepath.Join(dir, "experiment.txt")
+	ioutil.WriteFile(filename, []byte("{}"), 0644)
+
+	_, err = LoadExperimentSpec(filename)
+	assert.True(t, err != nil)
+
+}
+
+func TestValidateExperimentSpec(t *testing.T) {
+
+	registry := DefaultRegistry()
+
+	spec := &ExperimentSpec{
+		Trainer:    TrainerSpec{Type: TRAINER_POPULATION},
+		Mutators:   []string{"mutate_weights"},
+		Scape:      "xnor",
+		Population: PopulationSpec{Cortex: "xnor"},
+	}
+	assert.True(t, spec.Validate(registry) == nil)
+
+	spec.Scape = "checkers"
+	assert.True(t, spec.Validate(registry) != nil)
+	spec.Scape = "xnor"
+
+	spec.Mutators = []string{"no_such_mutator"}
+	assert.True(t, spec.Validate(registry) != nil)
+	spec.Mutators = []string{"mutate_weights"}
+
+	spec.Population.Size = 31
+	assert.True(t, spec.Validate(registry) != nil)
+	spec.Population.Size = 0
+
+	spec.Trainer.Type = TRAINER_STOCHASTIC_HILL_CLIMBER
+	assert.True(t, spec.Validate(registry) != nil)
+
+	spec.Mutators = nil
+	spec.Trainer.MaxAttempts = 10
+	spec.Trainer.MaxIterationsBeforeRestart = 100
+	spec.Trainer.WeightSaturationRange = []float64{-100, 100}
+	assert.True(t, spec.Validate(registry) == nil)
+
+	spec.Trainer.Type = "simulated_annealing"
+	assert.True(t, spec.Validate(registry) != nil)
+
+}
+
+func TestRunExperimentPortInUse(t *testing.T) {
+
+	listener, err := net.Listen("tcp", ":0")
+	assert.True(t, err == nil)
+	defer listener.Close()
+
+	spec := &ExperimentSpec{
+		Trainer:    TrainerSpec{Type: TRAINER_POPULATION, MaxGenerations: 1},
+		Mutators:   []string{"mutate_weights"},
+		Scape:      "xnor",
+		Population: PopulationSpec{Cortex: "xnor"},
+		HttpPort:   listener.Addr().(*net.TCPAddr).Port,
+	}
+	_, err = spec.Run(DefaultRegistry())
+	assert.True(t, err != nil)
+
+}
@@ -0,0 +1,71 @@
+package neurvolve
+
+import (
+	"github.com/couchbaselabs/logg"
+	"math"
+	"sort"
+)
+
+// Combines several noisy fitness scores for the same cortex into a
+// single fitness value.  Useful for scapes with random starting conditions,
+// where a single evaluation might just be lucky.
+type FitnessAggregator func(scores []float64) float64
+
+func AggregateMean(scores []float64) float64 {
+	if len(scores) == 0 {
+		return 0.0
+	}
+	sum := 0.0
+	for _, score := range scores {
+		sum += score
+	}
+	return sum / float64(len(scores))
+}
+
+func AggregateMedian(scores []float64) float64 {
+	if len(scores) == 0 {
+		return 0.0
+	}
+	sorted := make([]float64, len(scores))
+	copy(sorted, scores)
+	sort.Float64s(sorted)
+	middle := len(sorted) / 2
+	if len(sorted)%2 == 0 {
+		return (sorted[middle-1] + sorted[middle]) / 2
+	}
+	return sorted[middle]
+}
+
+func AggregateMin(scores []float64) float64 {
+	if len(scores) == 0 {
+		return 0.0
+	}
+	min := scores[0]
+	for _, score := range scores[1:] {
+		min = math.Min(min, score)
+	}
+	return min
+}
+
+// Returns an aggregator which computes mean - z * standard error, so that
+// cortexes which have only been evaluated a few times (or whose scores vary
+// a lot) are penalized relative to consistently good ones.  A z of 1.96
+// corresponds to the lower bound of a 95% confidence interval.
+func AggregateLowerConfidenceBound(z float64) FitnessAggregator {
+	if z < 0 {
+		logg.LogPanic("z must be non-negative, got: %v", z)
+	}
+	return func(scores []float64) float64 {
+		if len(scores) < 2 {
+			return AggregateMean(scores)
+		}
+		mean := AggregateMean(scores)
+		sumSquares := 0.0
+		for _, score := range scores {
+			sumSquares += (score - mean) * (score - mean)
+		}
+		n := float64(len(scores))
+		stdDev := math.Sqrt(sumSquares / (n - 1))
+		return mean - z*stdDev/math.Sqrt(n)
+	}
+}
@@ -0,0 +1,48 @@
+package neurvolve
+
+import (
+	"github.com/couchbaselabs/go.assert"
+	"math"
+	"testing"
+)
+
+func TestAggregateMean(t *testing.T) {
+	assert.Equals(t, AggregateMean([]float64{1, 2, 3, 6}), 3.0)
+	assert.Equals(t, AggregateMean([]float64{}), 0.0)
+}
+
+func TestAggregateMedian(t *testing.T) {
+	assert.Equals(t, AggregateMedian([]float64{5, 1, 3}), 3.0)
+	assert.Equals(t, AggregateMedian([]float64{4, 1, 3, 2}), 2.5)
+
+	// should not reorder the caller's scores
+	scores := []float64{5, 1, 3}
+	AggregateMedian(scores)
+	assert.Equals(t, scores[0], 5.0)
+}
+
+func TestAggregateMin(t *testing.T) {
+	assert.Equals(t, AggregateMin([]float64{5, -1, 3}), -1.0)
+}
+
+func TestAggregateLowerConfidenceBound(t *testing.T) {
+
+	aggregator := AggregateLowerConfidenceBound(2.0)
+
+	// a single score has no spread, so it's just the mean
+	assert.Equals(t, aggregator([]float64{10}), 10.0)
+
+	// identical scores have no spread either
+	assert.Equals(t, aggregator([]float64{10, 10, 10}), 10.0)
+
+	// mean 10, sample variance 16/3, n = 4
+	lcb := aggregator([]float64{8, 12, 8, 12})
+	expected := 10.0 - 2.0*math.Sqrt(16.0/3.0)/2.0
+	assert.True(t, math.Abs(lcb-expected) < 1e-9)
+
+	// noisy cortex should rank below a consistent one with the same mean
+	consistent := aggregator([]float64{10, 10, 10, 10})
+	noisy := aggregator([]float64{0, 20, 0, 20})
+	assert.True(t, noisy < consistent)
+
+}
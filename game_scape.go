@@ -0,0 +1,207 @@
+package neurvolve
+
+import (
+	"github.com/couchbaselabs/logg"
+	ng "github.com/maxxk/neurgo"
+	"math/rand"
+)
+
+// Scores of a finished game
+const (
+	GAME_SCORE_WIN  = 1.0
+	GAME_SCORE_DRAW = 0.5
+	GAME_SCORE_LOSS = 0.0
+)
+
+const DEFAULT_NUM_GAMES = 2
+
+// A position in a game, whose concrete type is chosen by the Game
+type GameState interface{}
+
+// A turn based game between two players, numbered 0 and 1, where
+// player 0 moves first.  States are never modified, Play returns a new one.
+type Game interface {
+
+	// The position at the start of the game
+	InitialState() GameState
+
+	// The player whose turn it is, 0 or 1
+	PlayerToMove(state GameState) int
+
+	// The position as the inputs of each sensor of a cortex, from the
+	// point of view of the player to move
+	Encode(state GameState) [][]float64
+
+	LegalMoves(state GameState) []int
+
+	// The legal move chosen by the outputs of each actuator of a cortex
+	DecodeMove(state GameState, outputs [][]float64) int
+
+	// The position after the player to move makes the move
+	Play(state GameState, move int) GameState
+
+	// Whether the game is over, and if so the score of each player
+	Score(state GameState) (over bool, scores [2]float64)
+}
+
+type GamePlayer interface {
+	ChooseMove(game Game, state GameState) int
+}
+
+// Plays a uniformly random legal move
+type RandomGamePlayer struct{}
+
+// Plays the moves chosen by a running cortex, which keeps its recurrent
+// state from one move to the next
+type CortexGamePlayer struct {
+	stepper *CortexStepper
+}
+
+// Evaluates cortexes by playing a Game, either against each other or,
+// for Fitness, against the Opponent.  The fitness is the mean score of
+// the cortex over NumGames games, in half of which it moves first.
+type GameScape struct {
+	Game Game
+
+	// Defaults to DEFAULT_NUM_GAMES
+	NumGames int
+
+	// Opponent for Fitness, defaults to a RandomGamePlayer
+	Opponent GamePlayer
+}
+
+func NewGameScape(game Game) *GameScape {
+	return &GameScape{Game: game}
+}
+
+func (scape GameScape) Fitness(cortex *ng.Cortex) float64 {
+	return scape.meanScore(cortex, nil)
+}
+
+func (scape GameScape) FitnessAgainst(cortex *ng.Cortex, opponent *ng.Cortex) float64 {
+	if cortex == opponent {
+		logg.LogPanic("Cannot play a cortex against itself")
+	}
+	return scape.meanScore(cortex, opponent)
+}
+
+// Play NumGames games, each with fresh players, and return the mean score
+// of the cortex.  If opponentCortex is nil, the Opponent is played instead.
+func (scape GameScape) meanScore(cortex *ng.Cortex, opponentCortex *ng.Cortex) float64 {
+
+	numGames := scape.numGames()
+	total := 0.0
+	for i := 0; i < numGames; i++ {
+
+		player := NewCortexGamePlayer(cortex)
+		var opponent GamePlayer = scape.opponent()
+		var opponentPlayer *CortexGamePlayer
+		if opponentCortex != nil {
+			opponentPlayer = NewCortexGamePlayer(opponentCortex)
+			opponent = opponentPlayer
+		}
+
+		seat := i % 2
+		var players [2]GamePlayer
+		players[seat] = player
+		players[1-seat] = opponent
+
+		scores := PlayGame(scape.Game, players)
+		total += scores[seat]
+
+		player.Stop()
+		if opponentPlayer != nil {
+			opponentPlayer.Stop()
+		}
+
+	}
+	return total / float64(numGames)
+
+}
+
+func (scape GameScape) numGames() int {
+	if scape.NumGames <= 0 {
+		return DEFAULT_NUM_GAMES
+	}
+	return scape.NumGames
+}
+
+func (scape GameScape) opponent() GamePlayer {
+	if scape.Opponent == nil {
+		return RandomGamePlayer{}
+	}
+	return scape.Opponent
+}
+
+// Play a game to the end and return the score of each player
+func PlayGame(game Game, players [2]GamePlayer) (scores [2]float64) {
+	state := game.InitialState()
+	for {
+		over, scores := game.Score(state)
+		if over {
+			return scores
+		}
+		move := players[game.PlayerToMove(state)].ChooseMove(game, state)
+		state = game.Play(state, move)
+	}
+}
+
+func (player RandomGamePlayer) ChooseMove(game Game, state GameState) int {
+	legalMoves := game.LegalMoves(state)
+	return legalMoves[rand.Intn(len(legalMoves))]
+}
+
+// Start running the cortex for a game.  The player must be stopped
+// when the game is over.
+func NewCortexGamePlayer(cortex *ng.Cortex) *CortexGamePlayer {
+	return &CortexGamePlayer{stepper: StartCortex(cortex)}
+}
+
+func (player *CortexGamePlayer) ChooseMove(game Game, state GameState) int {
+	outputs := player.stepper.Step(game.Encode(state))
+	return game.DecodeMove(state, outputs)
+}
+
+func (player *CortexGamePlayer) Stop() {
+	player.stepper.Stop()
+}
+
+// The legal move with the largest output, for games with one output
+// per possible move
+func DecodeBestLegalMove(outputs []float64, legalMoves []int) int {
+	if len(legalMoves) == 0 {
+		logg.LogPanic("No legal moves")
+	}
+	best := legalMoves[0]
+	for _, move := range legalMoves {
+		if outputs[move] > outputs[best] {
+			best = move
+		}
+	}
+	return best
+}
+
+// A cortex with a single sensor and actuator of the given lengths,
+// where each output neuron is connected to the sensor, to start
+// evolving players of a Game
+func GameCortex(sensorLength, actuatorLength int) *ng.Cortex {
+	factory := &PopulationFactory{
+		Sensors:   []SensorSpec{{Name: "board", VectorLength: sensorLength}},
+		Actuators: []ActuatorSpec{{Name: "move", VectorLength: actuatorLength}},
+		Template:  TEMPLATE_MINIMAL,
+	}
+	return factory.NewCortex()
+}
+
+// Scores for a game won by the winner, or drawn if winner is -1
+func gameScores(winner int) (scores [2]float64) {
+	switch winner {
+	case -1:
+		scores = [2]float64{GAME_SCORE_DRAW, GAME_SCORE_DRAW}
+	case 0:
+		scores = [2]float64{GAME_SCORE_WIN, GAME_SCORE_LOSS}
+	case 1:
+		scores = [2]float64{GAME_SCORE_LOSS, GAME_SCORE_WIN}
+	}
+	return
+}
@@ -0,0 +1,151 @@
+package neurvolve
+
+import (
+	"github.com/couchbaselabs/go.assert"
+	ng "github.com/maxxk/neurgo"
+	"testing"
+)
+
+// Plays the given moves in order
+type ScriptedGamePlayer struct {
+	moves []int
+}
+
+func (player *ScriptedGamePlayer) ChooseMove(game Game, state GameState) int {
+	move := player.moves[0]
+	player.moves = player.moves[1:]
+	return move
+}
+
+// Plays the lowest legal move
+type FirstLegalMovePlayer struct{}
+
+func (player FirstLegalMovePlayer) ChooseMove(game Game, state GameState) int {
+	return game.LegalMoves(state)[0]
+}
+
+// A tic-tac-toe cortex which always plays the lowest empty square
+func FirstLegalMoveCortex() *ng.Cortex {
+	cortex := GameCortex(9, 9)
+	for i, neuron := range cortex.Neurons {
+		neuron.ActivationFunction = ng.EncodableIdentity()
+		neuron.Bias = float64(-i)
+		for _, inbound := range neuron.Inbound {
+			for j := range inbound.Weights {
+				inbound.Weights[j] = 0
+			}
+		}
+	}
+	return cortex
+}
+
+func TestTicTacToe(t *testing.T) {
+
+	game := TicTacToe{}
+
+	// X wins on the diagonal
+	players := [2]GamePlayer{
+		&ScriptedGamePlayer{moves: []int{0, 4, 8}},
+		&ScriptedGamePlayer{moves: []int{1, 2}},
+	}
+	assert.Equals(t, PlayGame(game, players), [2]float64{GAME_SCORE_WIN, GAME_SCORE_LOSS})
+
+	// draw
+	players = [2]GamePlayer{
+		&ScriptedGamePlayer{moves: []int{0, 2, 5, 6, 7}},
+		&ScriptedGamePlayer{moves: []int{1, 3, 4, 8}},
+	}
+	assert.Equals(t, PlayGame(game, players), [2]float64{GAME_SCORE_DRAW, GAME_SCORE_DRAW})
+
+	state := game.Play(game.Play(game.InitialState(), 4), 0)
+	assert.Equals(t, game.PlayerToMove(state), 0)
+	assert.Equals(t, len(game.LegalMoves(state)), 7)
+	encoded := game.Encode(state)[0]
+	assert.Equals(t, encoded[4], 1.0)
+	assert.Equals(t, encoded[0], -1.0)
+	assert.Equals(t, encoded[1], 0.0)
+
+}
+
+func TestConnectFour(t *testing.T) {
+
+	game := ConnectFour{}
+
+	// vertical win for the second player
+	players := [2]GamePlayer{
+		&ScriptedGamePlayer{moves: []int{0, 1, 0, 1}},
+		&ScriptedGamePlayer{moves: []int{3, 3, 3, 3}},
+	}
+	assert.Equals(t, PlayGame(game, players), [2]float64{GAME_SCORE_LOSS, GAME_SCORE_WIN})
+
+	// diagonal win for the first player, from (0, 0) to (3, 3)
+	players = [2]GamePlayer{
+		&ScriptedGamePlayer{moves: []int{0, 1, 2, 2, 3, 3}},
+		&ScriptedGamePlayer{moves: []int{1, 2, 3, 3, 6}},
+	}
+	assert.Equals(t, PlayGame(game, players), [2]float64{GAME_SCORE_WIN, GAME_SCORE_LOSS})
+
+	// a full column is not a legal move
+	state := game.InitialState()
+	for i := 0; i < CONNECT_FOUR_ROWS; i++ {
+		state = game.Play(state, 6)
+	}
+	assert.Equals(t, len(game.LegalMoves(state)), CONNECT_FOUR_COLUMNS-1)
+	over, _ := game.Score(state)
+	assert.False(t, over)
+
+}
+
+func TestDecodeBestLegalMove(t *testing.T) {
+	outputs := []float64{5, 1, 3, 4}
+	assert.Equals(t, DecodeBestLegalMove(outputs, []int{1, 2, 3}), 3)
+	assert.Equals(t, DecodeBestLegalMove(outputs, []int{1}), 1)
+}
+
+func TestGameScapeAlternatesFirstMove(t *testing.T) {
+
+	// when both players play the lowest empty square, the first player
+	// wins on the diagonal 2, 4, 6
+	scape := NewGameScape(TicTacToe{})
+	scape.Opponent = FirstLegalMovePlayer{}
+
+	scape.NumGames = 1
+	assert.Equals(t, scape.Fitness(FirstLegalMoveCortex()), GAME_SCORE_WIN)
+
+	scape.NumGames = 2
+	assert.Equals(t, scape.Fitness(FirstLegalMoveCortex()), (GAME_SCORE_WIN+GAME_SCORE_LOSS)/2)
+	assert.Equals(t, scape.FitnessAgainst(FirstLegalMoveCortex(), FirstLegalMoveCortex()), (GAME_SCORE_WIN+GAME_SCORE_LOSS)/2)
+
+}
+
+func TestGameScapeCoevolution(t *testing.T) {
+
+	for _, game := range []Game{TicTacToe{}, ConnectFour{}} {
+
+		newCortex := func() *ng.Cortex { return GameCortex(9, 9) }
+		if _, ok := game.(ConnectFour); ok {
+			newCortex = func() *ng.Cortex {
+				return GameCortex(CONNECT_FOUR_ROWS*CONNECT_FOUR_COLUMNS, CONNECT_FOUR_COLUMNS)
+			}
+		}
+		population := make([]*ng.Cortex, 0)
+		for i := 0; i < 6; i++ {
+			population = append(population, newCortex())
+		}
+
+		pt := &PopulationTrainer{
+			FitnessThreshold: 1000,
+			MaxGenerations:   3,
+			NumOpponents:     2,
+			CortexMutator:    MutateWeights,
+		}
+		trainedPopulation, succeeded := pt.Train(population, NewGameScape(game), NewNullRecorder())
+		assert.False(t, succeeded)
+		assert.Equals(t, len(trainedPopulation), len(population))
+		for _, evaldCortex := range trainedPopulation {
+			assert.True(t, evaldCortex.Fitness >= GAME_SCORE_LOSS && evaldCortex.Fitness <= GAME_SCORE_WIN)
+		}
+
+	}
+
+}
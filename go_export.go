@@ -0,0 +1,357 @@
+package neurvolve
+
+import (
+	"bytes"
+	"fmt"
+	ng "github.com/maxxk/neurgo"
+	"go/format"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+const (
+	DEFAULT_EXPORT_PACKAGE_NAME = "champion"
+	DEFAULT_EXPORT_FUNC_NAME    = "Compute"
+)
+
+// Tolerance used by the generated test, since the order in which
+// neurgo sums the inputs of a neuron is not fixed
+const EXPORT_TEST_TOLERANCE = 1e-9
+
+type GoExportOptions struct {
+
+	// Defaults to DEFAULT_EXPORT_PACKAGE_NAME
+	PackageName string
+
+	// Defaults to DEFAULT_EXPORT_FUNC_NAME
+	FuncName string
+}
+
+type exportedActivation struct {
+	funcName string
+	source   string
+}
+
+// Go source for each supported activation function, keyed by the name
+// of the neurgo EncodableActivation
+var exportedActivations = map[string]exportedActivation{
+	ng.EncodableSigmoid().Name: {
+		funcName: "sigmoid",
+		source:   "func sigmoid(x float64) float64 {\n\treturn 1.0 / (1.0 + math.Exp(-x))\n}\n",
+	},
+	ng.EncodableTanh().Name: {
+		funcName: "tanh",
+		source:   "func tanh(x float64) float64 {\n\treturn math.Tanh(x)\n}\n",
+	},
+	ng.EncodableIdentity().Name: {
+		funcName: "identity",
+		source:   "func identity(x float64) float64 {\n\treturn x\n}\n",
+	},
+}
+
+// A node whose output is a variable in the generated function.
+// Sensors are []float64 variables, neurons are float64 variables.
+type exportedNode struct {
+	variable     string
+	vectorLength int
+	isSensor     bool
+}
+
+// Write a self-contained Go source file with a pure function which
+// computes the same actuator outputs as the (non-recurrent) cortex.
+// The function takes one input vector per sensor and returns one
+// output vector per actuator, in the order of cortex.Sensors and
+// cortex.Actuators, which is the layout of ng.TrainingSample.
+func ExportGo(cortex *ng.Cortex, options GoExportOptions, w io.Writer) error {
+
+	options = options.withDefaults()
+
+	body, activations, err := exportFuncBody(cortex)
+	if err != nil {
+		return err
+	}
+
+	buffer := &bytes.Buffer{}
+	fmt.Fprintf(buffer, "// Code generated by neurvolve from cortex %v. DO NOT EDIT.\n\n", cortex.NodeId.UUID)
+	fmt.Fprintf(buffer, "package %v\n\n", options.PackageName)
+	if usesMath(activations) {
+		fmt.Fprintf(buffer, "import \"math\"\n\n")
+	}
+	fmt.Fprintf(buffer, "// Computes the actuator outputs of cortex %v, one vector per\n", cortex.NodeId.UUID)
+	fmt.Fprintf(buffer, "// actuator, from the sensor inputs, one vector per sensor.\n")
+	fmt.Fprintf(buffer, "func %v(inputs [][]float64) [][]float64 {\n", options.FuncName)
+	buffer.WriteString(body)
+	fmt.Fprintf(buffer, "}\n")
+	for _, name := range activations {
+		fmt.Fprintf(buffer, "\n%v", exportedActivations[name].source)
+	}
+
+	return writeFormatted(buffer.Bytes(), w)
+
+}
+
+// Write a test for the code generated by ExportGo, which checks that it
+// produces the same outputs as the cortex does for the inputs of the samples.
+// The cortex is run to get the expected outputs, so ExpectedOutputs of the
+// samples are ignored.
+func ExportGoTest(cortex *ng.Cortex, samples []*ng.TrainingSample, options GoExportOptions, w io.Writer) error {
+
+	options = options.withDefaults()
+
+	expectedOutputs := CortexOutputs(cortex, samples)
+
+	buffer := &bytes.Buffer{}
+	fmt.Fprintf(buffer, "// Code generated by neurvolve from cortex %v. DO NOT EDIT.\n\n", cortex.NodeId.UUID)
+	fmt.Fprintf(buffer, "package %v\n\n", options.PackageName)
+	fmt.Fprintf(buffer, "import (\n\"math\"\n\"testing\"\n)\n\n")
+	fmt.Fprintf(buffer, "func Test%v(t *testing.T) {\n\n", options.FuncName)
+	fmt.Fprintf(buffer, "samples := []struct {\ninputs [][]float64\nexpected [][]float64\n}{\n")
+	for i, sample := range samples {
+		inputs, err := exportVectors(sample.SampleInputs)
+		if err != nil {
+			return err
+		}
+		expected, err := exportVectors(expectedOutputs[i])
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(buffer, "{\ninputs: %v,\nexpected: %v,\n},\n", inputs, expected)
+	}
+	fmt.Fprintf(buffer, "}\n\n")
+	fmt.Fprintf(buffer, "for i, sample := range samples {\n")
+	fmt.Fprintf(buffer, "outputs := %v(sample.inputs)\n", options.FuncName)
+	fmt.Fprintf(buffer, "if len(outputs) != len(sample.expected) {\n")
+	fmt.Fprintf(buffer, "t.Fatalf(\"sample %%d: expected %%d outputs, got %%d\", i, len(sample.expected), len(outputs))\n}\n")
+	fmt.Fprintf(buffer, "for j, expected := range sample.expected {\n")
+	fmt.Fprintf(buffer, "if len(outputs[j]) != len(expected) {\n")
+	fmt.Fprintf(buffer, "t.Fatalf(\"sample %%d output %%d: expected %%v, got %%v\", i, j, expected, outputs[j])\n}\n")
+	fmt.Fprintf(buffer, "for k := range expected {\n")
+	fmt.Fprintf(buffer, "if math.Abs(outputs[j][k]-expected[k]) > %v {\n", formatFloat(EXPORT_TEST_TOLERANCE))
+	fmt.Fprintf(buffer, "t.Errorf(\"sample %%d output %%d: expected %%v, got %%v\", i, j, expected, outputs[j])\n")
+	fmt.Fprintf(buffer, "}\n}\n}\n}\n\n}\n")
+
+	return writeFormatted(buffer.Bytes(), w)
+
+}
+
+// Run the cortex on the inputs of each sample and collect the outputs
+// of its actuators, in the same layout as ng.TrainingSample.ExpectedOutputs
+func CortexOutputs(cortex *ng.Cortex, samples []*ng.TrainingSample) (outputs [][][]float64) {
+
+	outputs = make([][][]float64, len(samples))
+	stepper := StartCortex(cortex)
+	for i, sample := range samples {
+		outputs[i] = stepper.Step(sample.SampleInputs)
+	}
+	stepper.Stop()
+
+	return
+
+}
+
+func (options GoExportOptions) withDefaults() GoExportOptions {
+	if options.PackageName == "" {
+		options.PackageName = DEFAULT_EXPORT_PACKAGE_NAME
+	}
+	if options.FuncName == "" {
+		options.FuncName = DEFAULT_EXPORT_FUNC_NAME
+	}
+	return options
+}
+
+// The statements of the generated function, and the names of the
+// activation functions it uses
+func exportFuncBody(cortex *ng.Cortex) (body string, activations []string, err error) {
+
+	nodes := make(map[string]exportedNode)
+	buffer := &bytes.Buffer{}
+
+	// only nodes which are read are declared, since sensors and neurons
+	// without outbound connections would be unused variables
+	read := make(map[string]bool)
+	for _, neuron := range cortex.Neurons {
+		for _, inbound := range neuron.Inbound {
+			read[inbound.NodeId.UUID] = true
+		}
+	}
+	for _, actuator := range cortex.Actuators {
+		for _, inbound := range actuator.Inbound {
+			read[inbound.NodeId.UUID] = true
+		}
+	}
+
+	for i, sensor := range cortex.Sensors {
+		variable := fmt.Sprintf("s%d", i)
+		nodes[sensor.NodeId.UUID] = exportedNode{variable: variable, vectorLength: sensor.VectorLength, isSensor: true}
+		if read[sensor.NodeId.UUID] {
+			fmt.Fprintf(buffer, "%v := inputs[%d]\n", variable, i)
+		}
+	}
+
+	neurons, err := topologicalOrder(cortex)
+	if err != nil {
+		return "", nil, err
+	}
+
+	activationUsed := make(map[string]bool)
+	for i, neuron := range neurons {
+
+		if neuron.ActivationFunction == nil {
+			return "", nil, fmt.Errorf("Neuron %v has no activation function", neuron.NodeId.UUID)
+		}
+		activation := neuron.ActivationFunction.Name
+		exported, ok := exportedActivations[activation]
+		if !ok {
+			return "", nil, fmt.Errorf("Cannot export activation function %q of neuron %v", activation, neuron.NodeId.UUID)
+		}
+		if !activationUsed[activation] {
+			activationUsed[activation] = true
+			activations = append(activations, activation)
+		}
+
+		terms := make([]string, 0)
+		for _, inbound := range neuron.Inbound {
+			source := nodes[inbound.NodeId.UUID]
+			if len(inbound.Weights) != source.vectorLength {
+				return "", nil, fmt.Errorf("Neuron %v has %d weights for %v, expected %d", neuron.NodeId.UUID, len(inbound.Weights), inbound.NodeId.UUID, source.vectorLength)
+			}
+			for j, weight := range inbound.Weights {
+				if err := checkFinite(weight); err != nil {
+					return "", nil, err
+				}
+				input := source.variable
+				if source.isSensor {
+					input = fmt.Sprintf("%v[%d]", input, j)
+				}
+				terms = append(terms, fmt.Sprintf("%v*%v", formatFloat(weight), input))
+			}
+		}
+		if err := checkFinite(neuron.Bias); err != nil {
+			return "", nil, err
+		}
+		terms = append(terms, formatFloat(neuron.Bias))
+
+		variable := fmt.Sprintf("n%d", i)
+		nodes[neuron.NodeId.UUID] = exportedNode{variable: variable, vectorLength: 1}
+		if !read[neuron.NodeId.UUID] {
+			continue
+		}
+		fmt.Fprintf(buffer, "// neuron %v\n", neuron.NodeId.UUID)
+		fmt.Fprintf(buffer, "%v := %v(%v)\n", variable, exported.funcName, strings.Join(terms, " + "))
+
+	}
+
+	fmt.Fprintf(buffer, "return [][]float64{\n")
+	for _, actuator := range cortex.Actuators {
+		values := make([]string, 0)
+		for _, inbound := range actuator.Inbound {
+			source, ok := nodes[inbound.NodeId.UUID]
+			if !ok || source.isSensor {
+				return "", nil, fmt.Errorf("Actuator %v has an inbound connection from unknown neuron %v", actuator.NodeId.UUID, inbound.NodeId.UUID)
+			}
+			values = append(values, source.variable)
+		}
+		fmt.Fprintf(buffer, "{%v},\n", strings.Join(values, ", "))
+	}
+	fmt.Fprintf(buffer, "}\n")
+
+	return buffer.String(), activations, nil
+
+}
+
+// Order the neurons so that each one comes after all of its inputs,
+// which fails if the cortex is recurrent
+func topologicalOrder(cortex *ng.Cortex) (ordered []*ng.Neuron, err error) {
+
+	sensors := make(map[string]bool)
+	for _, sensor := range cortex.Sensors {
+		sensors[sensor.NodeId.UUID] = true
+	}
+	neurons := make(map[string]bool)
+	for _, neuron := range cortex.Neurons {
+		neurons[neuron.NodeId.UUID] = true
+	}
+
+	done := make(map[string]bool)
+	for len(ordered) < len(cortex.Neurons) {
+		progress := false
+		for _, neuron := range cortex.Neurons {
+			if done[neuron.NodeId.UUID] {
+				continue
+			}
+			ready := true
+			for _, inbound := range neuron.Inbound {
+				uuid := inbound.NodeId.UUID
+				switch {
+				case sensors[uuid] || done[uuid]:
+				case neurons[uuid]:
+					ready = false
+				default:
+					return nil, fmt.Errorf("Neuron %v has an inbound connection from unknown node %v", neuron.NodeId.UUID, uuid)
+				}
+			}
+			if ready {
+				done[neuron.NodeId.UUID] = true
+				ordered = append(ordered, neuron)
+				progress = true
+			}
+		}
+		if !progress {
+			return nil, fmt.Errorf("Cannot export recurrent cortex %v", cortex.NodeId.UUID)
+		}
+	}
+
+	return ordered, nil
+
+}
+
+func usesMath(activations []string) bool {
+	for _, name := range activations {
+		if strings.Contains(exportedActivations[name].source, "math.") {
+			return true
+		}
+	}
+	return false
+}
+
+func exportVectors(vectors [][]float64) (string, error) {
+	exported := make([]string, 0)
+	for _, vector := range vectors {
+		values := make([]string, 0)
+		for _, value := range vector {
+			if err := checkFinite(value); err != nil {
+				return "", err
+			}
+			values = append(values, formatFloat(value))
+		}
+		exported = append(exported, fmt.Sprintf("{%v}", strings.Join(values, ", ")))
+	}
+	return fmt.Sprintf("[][]float64{%v}", strings.Join(exported, ", ")), nil
+}
+
+func checkFinite(value float64) error {
+	if math.IsNaN(value) || math.IsInf(value, 0) {
+		return fmt.Errorf("Cannot export non-finite value %v", value)
+	}
+	return nil
+}
+
+// Shortest representation which parses back to exactly the same float64
+func formatFloat(value float64) string {
+	formatted := strconv.FormatFloat(value, 'g', -1, 64)
+	if value < 0 {
+		return "(" + formatted + ")"
+	}
+	return formatted
+}
+
+func writeFormatted(source []byte, w io.Writer) error {
+	formatted, err := format.Source(source)
+	if err != nil {
+		return fmt.Errorf("Generated invalid Go source: %v", err)
+	}
+	_, err = w.Write(formatted)
+	return err
+}
@@ -0,0 +1,122 @@
+package neurvolve
+
+import (
+	"bytes"
+	"github.com/couchbaselabs/go.assert"
+	ng "github.com/maxxk/neurgo"
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestExportGo(t *testing.T) {
+
+	buffer := &bytes.Buffer{}
+	options := GoExportOptions{PackageName: "xnor", FuncName: "Xnor"}
+	err := ExportGo(BasicCortex(), options, buffer)
+	assert.True(t, err == nil)
+
+	source := buffer.String()
+	assert.True(t, strings.Contains(source, "package xnor"))
+	assert.True(t, strings.Contains(source, "func Xnor(inputs [][]float64) [][]float64"))
+	assert.True(t, strings.Contains(source, "func sigmoid("))
+	assert.False(t, strings.Contains(source, "func tanh("))
+
+}
+
+func TestExportGoRecurrent(t *testing.T) {
+
+	err := ExportGo(BasicCortexRecurrent(), GoExportOptions{}, &bytes.Buffer{})
+	assert.True(t, err != nil)
+
+}
+
+// Sensors and neurons which nothing reads, as made by sparse templates
+// and mutations, must not become unused variables
+func TestExportGoUnreadNodes(t *testing.T) {
+
+	cortex := SingleNeuronCortex("cortex")
+	sensor := cortex.Sensors[0]
+
+	unreadSensor := &ng.Sensor{
+		NodeId:       ng.NewSensorId("unread-sensor", 0.0),
+		VectorLength: 1,
+	}
+	unreadSensor.Init()
+
+	unreadNeuron := &ng.Neuron{
+		ActivationFunction: ng.EncodableIdentity(),
+		NodeId:             ng.NewNeuronId("unread-neuron", 0.25),
+	}
+	unreadNeuron.Init()
+	sensor.ConnectOutbound(unreadNeuron)
+	unreadNeuron.ConnectInboundWeighted(sensor, []float64{1})
+
+	cortex.SetSensors([]*ng.Sensor{sensor, unreadSensor})
+	cortex.SetNeurons(append(cortex.Neurons, unreadNeuron))
+
+	source := &bytes.Buffer{}
+	assert.True(t, ExportGo(cortex, GoExportOptions{}, source) == nil)
+
+	fileSet := token.NewFileSet()
+	file, err := parser.ParseFile(fileSet, "champion.go", source, 0)
+	assert.True(t, err == nil)
+	config := types.Config{Importer: importer.ForCompiler(fileSet, "source", nil)}
+	_, err = config.Check("champion", fileSet, []*ast.File{file}, nil)
+	if err != nil {
+		t.Fatalf("generated code does not compile: %v\n%v", err, source)
+	}
+
+}
+
+// Compile the generated code along with its generated test, which
+// compares it against the outputs of the cortex
+func TestExportGoMatchesCortex(t *testing.T) {
+
+	if testing.Short() {
+		t.Skip("skipping go test of generated code in short mode")
+	}
+	goBinary, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip("go binary not found")
+	}
+
+	dir, err := ioutil.TempDir("", "neurvolve-export")
+	assert.True(t, err == nil)
+	defer os.RemoveAll(dir)
+
+	cortex := BasicCortex()
+	samples := ng.XnorTrainingSamples()
+
+	source := &bytes.Buffer{}
+	assert.True(t, ExportGo(cortex, GoExportOptions{}, source) == nil)
+	testSource := &bytes.Buffer{}
+	assert.True(t, ExportGoTest(cortex, samples, GoExportOptions{}, testSource) == nil)
+
+	files := map[string][]byte{
+		"go.mod":           []byte("module champion\n"),
+		"champion.go":      source.Bytes(),
+		"champion_test.go": testSource.Bytes(),
+	}
+	for name, contents := range files {
+		err := ioutil.WriteFile(filepath.Join(dir, name), contents, 0644)
+		assert.True(t, err == nil)
+	}
+
+	cmd := exec.Command(goBinary, "test", ".")
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "GO111MODULE=on", "GOFLAGS=-mod=mod")
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("generated code failed its test: %v\n%s", err, output)
+	}
+
+}
@@ -3,111 +3,436 @@ package neurvolve
 import (
 	"encoding/json"
 	"fmt"
-	_ "github.com/couchbaselabs/logg"
+	"github.com/couchbaselabs/logg"
 	"github.com/gorilla/mux"
 	ng "github.com/maxxk/neurgo"
 	"net/http"
-	"time"
+	"strconv"
 )
 
 type PopulationStore interface {
-	GetPopulationSnapshot() EvaluatedCortexes
+
+	// The latest evaluated generation, or nil if there isn't one yet.
+	// Must not block.
+	GetPopulationSnapshot() *PopulationSnapshot
+}
+
+// Optionally implemented by a PopulationStore to expose training progress
+type MetricsStore interface {
+	GetMetricsHistory() *MetricsHistory
+}
+
+// Optionally implemented by a PopulationStore to allow controlling training
+type TrainingController interface {
+	GetTrainingControl() *TrainingControl
+}
+
+// Optionally implemented by a PopulationStore to stream training events
+type EventSource interface {
+	GetEventBroadcaster() *EventBroadcaster
+}
+
+// Optionally implemented by a PopulationStore to allow saving cortexes
+type ArtifactSource interface {
+	GetArtifactStore() ArtifactStore
+}
+
+// Settings which can be changed while training is running
+type TrainingSettings struct {
+	FitnessThreshold *float64 `json:"fitness_threshold"`
+	MaxGenerations   *int     `json:"max_generations"`
+}
+
+// Errors are returned to clients as {"error": {"status": 404, "message": "..."}}
+type ErrorResponse struct {
+	Error ErrorDetail `json:"error"`
 }
 
-func RegisterHandlers(pt PopulationStore) {
+type ErrorDetail struct {
+	Status  int    `json:"status"`
+	Message string `json:"message"`
+}
+
+// Mount the handlers at the root of the given ServeMux
+func RegisterHandlers(serveMux *http.ServeMux, pt PopulationStore) {
+	serveMux.Handle("/", NewHandler(pt))
+}
+
+// Returns a handler serving the population held by the PopulationStore,
+// which can be mounted on any router
+func NewHandler(pt PopulationStore) http.Handler {
 
 	r := mux.NewRouter()
+	r.NotFoundHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeJsonError(w, http.StatusNotFound, "No route for %v", r.URL.Path)
+	})
+
+	withSnapshot := func(handler func(*PopulationSnapshot, http.ResponseWriter, *http.Request)) func(http.ResponseWriter, *http.Request) {
+		return func(w http.ResponseWriter, r *http.Request) {
+			snapshot := pt.GetPopulationSnapshot()
+			if snapshot == nil {
+				writeJsonError(w, http.StatusServiceUnavailable, "No population snapshot available yet")
+				return
+			}
+			handler(snapshot, w, r)
+		}
+	}
 
-	showAllCortexes := func(w http.ResponseWriter, r *http.Request) {
-		evaldPopulation := pt.GetPopulationSnapshot()
-		marshalJson(evaldPopulation, w)
+	withCortex := func(handler func(*PopulationSnapshot, EvaluatedCortex, http.ResponseWriter, *http.Request)) func(http.ResponseWriter, *http.Request) {
+		return withSnapshot(func(snapshot *PopulationSnapshot, w http.ResponseWriter, r *http.Request) {
+			vars := mux.Vars(r)
+			cortexUuid := vars["cortex_uuid"]
+			evaldCortex, found := snapshot.Population.Find(cortexUuid)
+			if !found {
+				writeJsonError(w, http.StatusNotFound, "No cortex with uuid %v in generation %v", cortexUuid, snapshot.Generation)
+				return
+			}
+			handler(snapshot, evaldCortex, w, r)
+		})
 	}
-	showAllCortexUuids := func(w http.ResponseWriter, r *http.Request) {
-		evaldPopulation := pt.GetPopulationSnapshot()
-		uuids := evaldPopulation.Uuids()
+
+	withArtifacts := func(handler func(ArtifactStore, http.ResponseWriter, *http.Request)) func(http.ResponseWriter, *http.Request) {
+		return func(w http.ResponseWriter, r *http.Request) {
+			store := artifactStore(pt)
+			if store == nil {
+				writeJsonError(w, http.StatusServiceUnavailable, "No artifact store configured")
+				return
+			}
+			handler(store, w, r)
+		}
+	}
+
+	showAllCortexes := func(snapshot *PopulationSnapshot, w http.ResponseWriter, r *http.Request) {
+		marshalJson(snapshot, w)
+	}
+	showAllCortexUuids := func(snapshot *PopulationSnapshot, w http.ResponseWriter, r *http.Request) {
+		uuids := snapshot.Population.Uuids()
 		marshalJson(uuids, w)
 	}
 
-	saveAllCortexes := func(w http.ResponseWriter, r *http.Request) {
-		saveMap := make(map[string][]string)
-		evaldPopulation := pt.GetPopulationSnapshot()
-		for _, evaldCortex := range evaldPopulation {
-			filename, filenameSvg, filenameFitness := saveCortex(evaldCortex)
-			filenames := []string{filename, filenameSvg, filenameFitness}
-			saveMap[evaldCortex.Cortex.NodeId.UUID] = filenames
+	saveAllCortexes := func(snapshot *PopulationSnapshot, w http.ResponseWriter, r *http.Request) {
+		store := artifactStore(pt)
+		if store == nil {
+			writeJsonError(w, http.StatusServiceUnavailable, "No artifact store configured")
+			return
+		}
+		artifacts := ArtifactIndex{}
+		for _, evaldCortex := range snapshot.Population {
+			artifact, err := store.Save(evaldCortex, snapshot.Generation)
+			if err != nil {
+				writeJsonError(w, http.StatusInternalServerError, "Error saving cortex: %v", err)
+				return
+			}
+			artifacts = append(artifacts, artifact)
 		}
-		marshalJson(saveMap, w)
+		marshalJson(artifacts, w)
 	}
 
-	showCortex := func(w http.ResponseWriter, r *http.Request) {
-		evaldPopulation := pt.GetPopulationSnapshot()
-		vars := mux.Vars(r)
-		cortexUuid := vars["cortex_uuid"]
-		evaldCortex := evaldPopulation.Find(cortexUuid)
+	showCortex := func(snapshot *PopulationSnapshot, evaldCortex EvaluatedCortex, w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
 		fmt.Fprintf(w, "%v", evaldCortex.Cortex)
 	}
 
-	saveCortex := func(w http.ResponseWriter, r *http.Request) {
-		vars := mux.Vars(r)
-		cortexUuid := vars["cortex_uuid"]
-		evaldPopulation := pt.GetPopulationSnapshot()
-		evaldCortex := evaldPopulation.Find(cortexUuid)
-		filename, filenameSvg, filenameFitness := saveCortex(evaldCortex)
-		fmt.Fprintf(w, "Json: %v Svg: %v Fit: %v", filename, filenameSvg, filenameFitness)
+	saveCortex := func(snapshot *PopulationSnapshot, evaldCortex EvaluatedCortex, w http.ResponseWriter, r *http.Request) {
+		store := artifactStore(pt)
+		if store == nil {
+			writeJsonError(w, http.StatusServiceUnavailable, "No artifact store configured")
+			return
+		}
+		artifact, err := store.Save(evaldCortex, snapshot.Generation)
+		if err != nil {
+			writeJsonError(w, http.StatusInternalServerError, "Error saving cortex: %v", err)
+			return
+		}
+		marshalJson(artifact, w)
+	}
+
+	// Optional filters: ?uuid=..., ?generation=..., ?sort=fitness
+	showArtifacts := func(store ArtifactStore, w http.ResponseWriter, r *http.Request) {
+		index := store.Index()
+		query := r.URL.Query()
+		if uuid := query.Get("uuid"); uuid != "" {
+			index = index.FindByUuid(uuid)
+		}
+		if generationStr := query.Get("generation"); generationStr != "" {
+			generation, err := strconv.Atoi(generationStr)
+			if err != nil {
+				writeJsonError(w, http.StatusBadRequest, "Invalid generation: %v", generationStr)
+				return
+			}
+			index = index.FindByGeneration(generation)
+		}
+		if query.Get("sort") == "fitness" {
+			index = index.SortedByFitness()
+		}
+		marshalJson(index, w)
 	}
 
-	cortexSvgHandler := func(w http.ResponseWriter, r *http.Request) {
-		vars := mux.Vars(r)
-		cortexUuid := vars["cortex_uuid"]
-		evaldPopulation := pt.GetPopulationSnapshot()
-		evaldCortex := evaldPopulation.Find(cortexUuid)
+	cortexSvgHandler := func(snapshot *PopulationSnapshot, evaldCortex EvaluatedCortex, w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/svg+xml")
 		cortex := evaldCortex.Cortex
 		cortex.RenderSVG(w)
 	}
 
+	showMetrics := func(w http.ResponseWriter, r *http.Request) {
+		history := metricsHistory(pt)
+		if history == nil {
+			writeJsonError(w, http.StatusServiceUnavailable, "No metrics available")
+			return
+		}
+		marshalJson(history.All(), w)
+	}
+
+	showPrometheusMetrics := func(w http.ResponseWriter, r *http.Request) {
+		history := metricsHistory(pt)
+		if history == nil {
+			writeJsonError(w, http.StatusServiceUnavailable, "No metrics available")
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		history.WritePrometheus(w)
+	}
+
+	withControl := func(handler func(*TrainingControl, http.ResponseWriter, *http.Request)) func(http.ResponseWriter, *http.Request) {
+		return func(w http.ResponseWriter, r *http.Request) {
+			control := trainingControl(pt)
+			if control == nil {
+				writeJsonError(w, http.StatusServiceUnavailable, "Training control not available")
+				return
+			}
+			handler(control, w, r)
+		}
+	}
+
+	showTrainingStatus := func(control *TrainingControl, w http.ResponseWriter, r *http.Request) {
+		marshalJson(control.Status(), w)
+	}
+
+	pauseTraining := func(control *TrainingControl, w http.ResponseWriter, r *http.Request) {
+		control.Pause()
+		showTrainingStatus(control, w, r)
+	}
+
+	resumeTraining := func(control *TrainingControl, w http.ResponseWriter, r *http.Request) {
+		control.Resume()
+		showTrainingStatus(control, w, r)
+	}
+
+	stopTraining := func(control *TrainingControl, w http.ResponseWriter, r *http.Request) {
+		control.Stop()
+		showTrainingStatus(control, w, r)
+	}
+
+	changeTrainingSettings := func(control *TrainingControl, w http.ResponseWriter, r *http.Request) {
+		settings := TrainingSettings{}
+		if err := json.NewDecoder(r.Body).Decode(&settings); err != nil {
+			writeJsonError(w, http.StatusBadRequest, "Invalid settings: %v", err)
+			return
+		}
+		if settings.FitnessThreshold != nil {
+			control.SetFitnessThreshold(*settings.FitnessThreshold)
+		}
+		if settings.MaxGenerations != nil {
+			control.SetMaxGenerations(*settings.MaxGenerations)
+		}
+		showTrainingStatus(control, w, r)
+	}
+
+	injectCortex := func(control *TrainingControl, w http.ResponseWriter, r *http.Request) {
+		cortex := &ng.Cortex{}
+		if err := json.NewDecoder(r.Body).Decode(cortex); err != nil {
+			writeJsonError(w, http.StatusBadRequest, "Invalid cortex json: %v", err)
+			return
+		}
+		if cortex.NodeId == nil {
+			cortex.NodeId = ng.NewCortexId(fmt.Sprintf("cortex-%s", ng.NewUuid()))
+		}
+		if !cortex.Validate() {
+			writeJsonError(w, http.StatusBadRequest, "Cortex did not validate")
+			return
+		}
+		snapshot := pt.GetPopulationSnapshot()
+		if snapshot == nil || len(snapshot.Population) == 0 {
+			writeJsonError(w, http.StatusServiceUnavailable, "No population snapshot to check the cortex against yet")
+			return
+		}
+		if err := checkSameShape(cortex, snapshot.Population[0].Cortex); err != nil {
+			writeJsonError(w, http.StatusBadRequest, "Cortex does not fit the population: %v", err)
+			return
+		}
+		control.Inject(cortex)
+		showTrainingStatus(control, w, r)
+	}
+
+	streamEvents := func(w http.ResponseWriter, r *http.Request) {
+		broadcaster := eventBroadcaster(pt)
+		if broadcaster == nil {
+			writeJsonError(w, http.StatusServiceUnavailable, "Event stream not available")
+			return
+		}
+		flusher, ok := w.(http.Flusher)
+		if !ok {
+			writeJsonError(w, http.StatusInternalServerError, "Streaming not supported")
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Header().Set("Connection", "keep-alive")
+		flusher.Flush()
+
+		events := broadcaster.Subscribe()
+		defer broadcaster.Unsubscribe(events)
+
+		for {
+			select {
+			case event := <-events:
+				if err := writeServerSentEvent(w, event); err != nil {
+					return
+				}
+				flusher.Flush()
+			case <-r.Context().Done():
+				return
+			}
+		}
+	}
+
 	r.HandleFunc("/", HomeHandler)
-	r.HandleFunc("/cortex", showAllCortexes)
-	r.HandleFunc("/cortex/uuid", showAllCortexUuids)
-	r.HandleFunc("/cortex/save", saveAllCortexes)
-	r.HandleFunc("/cortex/{cortex_uuid}", showCortex)
-	r.HandleFunc("/cortex/{cortex_uuid}/save", saveCortex)
-	r.HandleFunc("/cortex/{cortex_uuid}/svg", cortexSvgHandler)
-	http.Handle("/", r)
+	r.HandleFunc("/dashboard", DashboardHandler)
+	r.HandleFunc("/events", streamEvents)
+	r.HandleFunc("/control", withControl(showTrainingStatus)).Methods("GET")
+	r.HandleFunc("/control/pause", withControl(pauseTraining)).Methods("POST")
+	r.HandleFunc("/control/resume", withControl(resumeTraining)).Methods("POST")
+	r.HandleFunc("/control/stop", withControl(stopTraining)).Methods("POST")
+	r.HandleFunc("/control/settings", withControl(changeTrainingSettings)).Methods("POST")
+	r.HandleFunc("/control/inject", withControl(injectCortex)).Methods("POST")
+	r.HandleFunc("/metrics", showMetrics)
+	r.HandleFunc("/metrics/prometheus", showPrometheusMetrics)
+	r.HandleFunc("/cortex", withSnapshot(showAllCortexes))
+	r.HandleFunc("/cortex/uuid", withSnapshot(showAllCortexUuids))
+	r.HandleFunc("/cortex/save", withSnapshot(saveAllCortexes))
+	r.HandleFunc("/artifacts", withArtifacts(showArtifacts))
+	r.HandleFunc("/cortex/{cortex_uuid}", withCortex(showCortex))
+	r.HandleFunc("/cortex/{cortex_uuid}/save", withCortex(saveCortex))
+	r.HandleFunc("/cortex/{cortex_uuid}/svg", withCortex(cortexSvgHandler))
+
+	return r
 
 }
 
 func HomeHandler(w http.ResponseWriter, r *http.Request) {
 	routeMap := make(map[string]string)
+	routeMap["/dashboard"] = "Browse the population in a web page"
 	routeMap["/cortex"] = "Show All Cortexes"
 	routeMap["/cortex/uuid"] = "Show All Cortex Uuids"
-	routeMap["/cortex/save"] = "Save All Cortexes to temp files"
+	routeMap["/cortex/save"] = "Save All Cortexes to the artifact store"
+	routeMap["/artifacts"] = "Show saved cortexes, optionally filtered by ?uuid= or ?generation= and sorted by ?sort=fitness"
 	routeMap["/cortex/{cortex_uuid}"] = "Show Cortex for uuid"
 	routeMap["/cortex/{cortex_uuid}/svg"] = "Show Cortex SVG for uuid"
-	routeMap["/cortex/{cortex_uuid}/save"] = "Save single cortex to temp file"
+	routeMap["/cortex/{cortex_uuid}/save"] = "Save single cortex to the artifact store"
+	routeMap["/metrics"] = "Show per-generation training statistics"
+	routeMap["/events"] = "Stream generation and match events (Server-Sent Events)"
+	routeMap["/control"] = "Show whether training is paused or stopped"
+	routeMap["/control/pause"] = "POST to pause training at the next generation"
+	routeMap["/control/resume"] = "POST to resume paused training"
+	routeMap["/control/stop"] = "POST to stop training at the next generation"
+	routeMap["/control/settings"] = "POST json with fitness_threshold and/or max_generations"
+	routeMap["/control/inject"] = "POST cortex json with the sensors and actuators of the population to add it"
+	routeMap["/metrics/prometheus"] = "Show latest training statistics in Prometheus format"
 	marshalJson(routeMap, w)
 }
 
+func eventBroadcaster(pt PopulationStore) *EventBroadcaster {
+	source, ok := pt.(EventSource)
+	if !ok {
+		return nil
+	}
+	return source.GetEventBroadcaster()
+}
+
+func writeServerSentEvent(w http.ResponseWriter, event TrainingEvent) error {
+	data, err := json.Marshal(event.Data)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event.Name, data)
+	return err
+}
+
+// An error unless the cortex has as many sensors and actuators as the
+// reference cortex, with the same vector lengths, so that it can be
+// evaluated by the same scape
+func checkSameShape(cortex, reference *ng.Cortex) error {
+	if len(cortex.Sensors) != len(reference.Sensors) {
+		return fmt.Errorf("has %d sensors instead of %d", len(cortex.Sensors), len(reference.Sensors))
+	}
+	for i, sensor := range cortex.Sensors {
+		if sensor.VectorLength != reference.Sensors[i].VectorLength {
+			return fmt.Errorf("sensor %d has vector length %d instead of %d", i, sensor.VectorLength, reference.Sensors[i].VectorLength)
+		}
+	}
+	if len(cortex.Actuators) != len(reference.Actuators) {
+		return fmt.Errorf("has %d actuators instead of %d", len(cortex.Actuators), len(reference.Actuators))
+	}
+	for i, actuator := range cortex.Actuators {
+		if actuator.VectorLength != reference.Actuators[i].VectorLength {
+			return fmt.Errorf("actuator %d has vector length %d instead of %d", i, actuator.VectorLength, reference.Actuators[i].VectorLength)
+		}
+	}
+	return nil
+}
+
+func trainingControl(pt PopulationStore) *TrainingControl {
+	controller, ok := pt.(TrainingController)
+	if !ok {
+		return nil
+	}
+	return controller.GetTrainingControl()
+}
+
+func artifactStore(pt PopulationStore) ArtifactStore {
+	source, ok := pt.(ArtifactSource)
+	if !ok {
+		return nil
+	}
+	return source.GetArtifactStore()
+}
+
+func metricsHistory(pt PopulationStore) *MetricsHistory {
+	metricsStore, ok := pt.(MetricsStore)
+	if !ok {
+		return nil
+	}
+	return metricsStore.GetMetricsHistory()
+}
+
 func marshalJson(v interface{}, w http.ResponseWriter) {
+	writeJson(w, http.StatusOK, v)
+}
+
+func writeJsonError(w http.ResponseWriter, status int, format string, args ...interface{}) {
+	errorResponse := ErrorResponse{
+		Error: ErrorDetail{
+			Status:  status,
+			Message: fmt.Sprintf(format, args...),
+		},
+	}
+	writeJson(w, status, errorResponse)
+}
+
+func writeJson(w http.ResponseWriter, status int, v interface{}) {
 	json, err := json.Marshal(v)
 	if err != nil {
-		fmt.Fprintf(w, "Error marshaling json: %v", err)
+		if status == http.StatusOK {
+			writeJsonError(w, http.StatusInternalServerError, "Error marshaling json: %v", err)
+		} else {
+			http.Error(w, fmt.Sprintf("Error marshaling json: %v", err), http.StatusInternalServerError)
+		}
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if _, err = w.Write(json); err != nil {
+		logg.LogTo("NEURVOLVE", "Error writing response: %v", err)
 	}
-	_, err = w.Write(json)
-	if err != nil {
-		fmt.Fprintf(w, "Error writing response: %v", err)
-	}
-}
-
-func saveCortex(evaldCortex EvaluatedCortex) (filename, filenameSvg, filenameFitness string) {
-	unixTimestamp := time.Now().Unix()
-	cortex := evaldCortex.Cortex
-	uuid := cortex.NodeId.UUID
-	filename = fmt.Sprintf("/tmp/%v-%v.json", uuid, unixTimestamp)
-	cortex.MarshalJSONToFile(filename)
-	filenameSvg = fmt.Sprintf("/tmp/%v-%v.svg", uuid, unixTimestamp)
-	cortex.RenderSVGFile(filenameSvg)
-	fitnessStr := fmt.Sprintf("%v", evaldCortex.Fitness)
-	filenameFitness = fmt.Sprintf("/tmp/%v-%v-%v.fit", uuid, unixTimestamp, fitnessStr)
-	ng.WriteStringToFile(fitnessStr, filenameFitness)
-	return
 }
@@ -0,0 +1,114 @@
+package neurvolve
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/couchbaselabs/go.assert"
+	ng "github.com/maxxk/neurgo"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestWriteJsonError(t *testing.T) {
+
+	w := httptest.NewRecorder()
+	writeJsonError(w, http.StatusNotFound, "No cortex with uuid %v", "foo")
+
+	assert.Equals(t, w.Code, http.StatusNotFound)
+	assert.Equals(t, w.Header().Get("Content-Type"), "application/json")
+
+	errorResponse := ErrorResponse{}
+	err := json.Unmarshal(w.Body.Bytes(), &errorResponse)
+	assert.True(t, err == nil)
+	assert.Equals(t, errorResponse.Error.Status, http.StatusNotFound)
+	assert.Equals(t, errorResponse.Error.Message, "No cortex with uuid foo")
+
+}
+
+func TestMarshalJsonUnsupportedValue(t *testing.T) {
+
+	w := httptest.NewRecorder()
+	marshalJson(make(chan int), w)
+
+	assert.Equals(t, w.Code, http.StatusInternalServerError)
+	assert.Equals(t, w.Header().Get("Content-Type"), "application/json")
+
+}
+
+func TestEvaluatedCortexesFind(t *testing.T) {
+
+	cortex := &ng.Cortex{NodeId: ng.NewCortexId("cortex")}
+	evaldCortexes := EvaluatedCortexes{EvaluatedCortex{Cortex: cortex, Fitness: 1.0}}
+
+	evaldCortex, found := evaldCortexes.Find(cortex.NodeId.UUID)
+	assert.True(t, found)
+	assert.Equals(t, evaldCortex.Cortex, cortex)
+
+	_, found = evaldCortexes.Find("no-such-uuid")
+	assert.False(t, found)
+
+}
+
+func TestInjectCortexChecksShape(t *testing.T) {
+
+	pt := &PopulationTrainer{Snapshots: NewSnapshotStore(), Control: NewTrainingControl()}
+	handler := NewHandler(pt)
+	inject := func(cortex *ng.Cortex) *httptest.ResponseRecorder {
+		body, err := json.Marshal(cortex)
+		assert.True(t, err == nil)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest("POST", "/control/inject", bytes.NewReader(body)))
+		return w
+	}
+
+	// nothing to compare with before the first generation
+	assert.Equals(t, inject(SingleNeuronCortex("injected")).Code, http.StatusServiceUnavailable)
+
+	pt.Snapshots.Publish(0, []EvaluatedCortex{{Cortex: SingleNeuronCortex("cortex")}})
+	assert.Equals(t, inject(BasicCortex()).Code, http.StatusBadRequest)
+	assert.Equals(t, len(pt.Control.checkpoint(1).injected), 0)
+
+	assert.Equals(t, inject(SingleNeuronCortex("injected")).Code, http.StatusOK)
+	assert.Equals(t, len(pt.Control.checkpoint(2).injected), 1)
+
+}
+
+func TestHandlerErrors(t *testing.T) {
+
+	pt := &PopulationTrainer{Snapshots: NewSnapshotStore()}
+	serveMux := http.NewServeMux()
+	RegisterHandlers(serveMux, pt)
+
+	for _, handler := range []http.Handler{NewHandler(pt), serveMux} {
+
+		get := func(path string, status int) {
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+			assert.Equals(t, w.Code, status)
+			assert.Equals(t, w.Header().Get("Content-Type"), "application/json")
+			errorResponse := ErrorResponse{}
+			err := json.Unmarshal(w.Body.Bytes(), &errorResponse)
+			assert.True(t, err == nil)
+			assert.Equals(t, errorResponse.Error.Status, status)
+			assert.True(t, errorResponse.Error.Message != "")
+		}
+
+		pt.Snapshots = NewSnapshotStore()
+		get("/cortex", http.StatusServiceUnavailable)
+		get("/cortex/uuid", http.StatusServiceUnavailable)
+		get("/no/such/route", http.StatusNotFound)
+
+		pt.Snapshots.Publish(0, []EvaluatedCortex{{Cortex: SingleNeuronCortex("cortex")}})
+		get("/cortex/no-such-uuid", http.StatusNotFound)
+		get("/cortex/no-such-uuid/svg", http.StatusNotFound)
+		get("/cortex/no-such-uuid/save", http.StatusNotFound)
+
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest("GET", "/cortex/uuid", nil))
+		assert.Equals(t, w.Code, http.StatusOK)
+		assert.Equals(t, w.Header().Get("Content-Type"), "application/json")
+
+	}
+
+}
@@ -0,0 +1,177 @@
+package neurvolve
+
+import (
+	"github.com/couchbaselabs/logg"
+	ng "github.com/maxxk/neurgo"
+	"math"
+)
+
+// Outputs are clamped to [CROSS_ENTROPY_EPSILON, 1 - CROSS_ENTROPY_EPSILON]
+// before taking logarithms
+const CROSS_ENTROPY_EPSILON = 1e-12
+
+// Fitness of a perfect score, since an infinite fitness can't be encoded
+// as json and turns the mean of a population's fitness into NaN.  It is
+// well above ng.FITNESS_THRESHOLD, so that threshold can still be reached.
+// Trainers also clamp the fitness given by any other scape to
+// [-MAX_FITNESS, MAX_FITNESS].
+const MAX_FITNESS = 1e12
+
+// The loss of a single sample, given its expected outputs and the
+// actual outputs of the cortex, with the vectors of all actuators
+// concatenated.  Lower is better and zero is perfect.
+type LossFunction func(expected, actual []float64) float64
+
+// What ng.Cortex.Fitness uses
+func LossSumOfSquares(expected, actual []float64) float64 {
+	return ng.SumOfSquaresError(expected, actual)
+}
+
+func LossMeanSquaredError(expected, actual []float64) float64 {
+	return LossSumOfSquares(expected, actual) / float64(len(expected))
+}
+
+func LossMeanAbsoluteError(expected, actual []float64) float64 {
+	sum := 0.0
+	for i := range expected {
+		sum += math.Abs(expected[i] - actual[i])
+	}
+	return sum / float64(len(expected))
+}
+
+// Binary cross-entropy for a single output, which should be a
+// probability.  For several outputs, the categorical cross-entropy
+// of the softmax of the outputs against the expected distribution,
+// typically a one-hot encoded class.
+func LossCrossEntropy(expected, actual []float64) float64 {
+
+	if len(actual) == 1 {
+		p := math.Min(math.Max(actual[0], CROSS_ENTROPY_EPSILON), 1-CROSS_ENTROPY_EPSILON)
+		return -(expected[0]*math.Log(p) + (1-expected[0])*math.Log(1-p))
+	}
+
+	probabilities := softmax(actual)
+	loss := 0.0
+	for i := range expected {
+		loss -= expected[i] * math.Log(math.Max(probabilities[i], CROSS_ENTROPY_EPSILON))
+	}
+	return loss
+
+}
+
+// Hinge loss for a single output with an expected value of -1 or 1.
+// For several outputs, the multiclass hinge loss where the expected
+// class is the largest expected output.
+func LossHinge(expected, actual []float64) float64 {
+
+	if len(actual) == 1 {
+		return math.Max(0, 1-expected[0]*actual[0])
+	}
+
+	class := argmax(expected)
+	maxOther := math.Inf(-1)
+	for i, output := range actual {
+		if i != class {
+			maxOther = math.Max(maxOther, output)
+		}
+	}
+	return math.Max(0, 1+maxOther-actual[class])
+
+}
+
+// 0 if the cortex chose the expected class and 1 otherwise, so that the
+// mean loss is the classification error rate.  A single output chooses
+// the positive class if it is at least the threshold, several outputs
+// choose the class of the largest one.
+func LossClassificationError(threshold float64) LossFunction {
+	return func(expected, actual []float64) float64 {
+		var correct bool
+		if len(actual) == 1 {
+			correct = (expected[0] >= threshold) == (actual[0] >= threshold)
+		} else {
+			correct = argmax(expected) == argmax(actual)
+		}
+		if correct {
+			return 0
+		}
+		return 1
+	}
+}
+
+// Fitness corresponding to a mean loss, which like ng.Cortex.Fitness
+// is its inverse, up to MAX_FITNESS for a perfect score
+func FitnessFromLoss(meanLoss float64) float64 {
+	return boundFitness(1 / meanLoss)
+}
+
+// Clamps a fitness to [-MAX_FITNESS, MAX_FITNESS].  NaN, eg from a scape
+// which divides by zero, is the worst fitness.
+func boundFitness(fitness float64) float64 {
+	if math.IsNaN(fitness) {
+		return -MAX_FITNESS
+	}
+	return math.Max(math.Min(fitness, MAX_FITNESS), -MAX_FITNESS)
+}
+
+// Weighted mean loss over the samples.  If weights is nil, every
+// sample has a weight of 1.  Samples whose weights add up to 0, eg a
+// mini-batch of samples which all have a weight of 0, have no loss
+// rather than a NaN one.
+func weightedMeanLoss(lossFunction LossFunction, samples []*ng.TrainingSample, outputs [][][]float64, weights []float64) float64 {
+
+	if weights != nil && len(weights) != len(samples) {
+		logg.LogPanic("Got %d sample weights for %d samples", len(weights), len(samples))
+	}
+
+	totalLoss := 0.0
+	totalWeight := 0.0
+	for i, sample := range samples {
+		weight := 1.0
+		if weights != nil {
+			weight = weights[i]
+		}
+		loss := lossFunction(concatenate(sample.ExpectedOutputs), concatenate(outputs[i]))
+		totalLoss += weight * loss
+		totalWeight += weight
+	}
+	if totalWeight == 0 {
+		return 0
+	}
+	return totalLoss / totalWeight
+
+}
+
+func concatenate(vectors [][]float64) []float64 {
+	if len(vectors) == 1 {
+		return vectors[0]
+	}
+	concatenated := make([]float64, 0)
+	for _, vector := range vectors {
+		concatenated = append(concatenated, vector...)
+	}
+	return concatenated
+}
+
+func softmax(values []float64) []float64 {
+	max := values[argmax(values)]
+	sum := 0.0
+	exps := make([]float64, len(values))
+	for i, value := range values {
+		exps[i] = math.Exp(value - max)
+		sum += exps[i]
+	}
+	for i := range exps {
+		exps[i] /= sum
+	}
+	return exps
+}
+
+func argmax(values []float64) int {
+	best := 0
+	for i, value := range values {
+		if value > values[best] {
+			best = i
+		}
+	}
+	return best
+}
@@ -0,0 +1,62 @@
+package neurvolve
+
+import (
+	"github.com/couchbaselabs/go.assert"
+	ng "github.com/maxxk/neurgo"
+	"math"
+	"testing"
+)
+
+func TestLossFunctions(t *testing.T) {
+
+	expected := []float64{1, 0}
+	actual := []float64{0.5, 1}
+
+	assert.Equals(t, LossSumOfSquares(expected, actual), 1.25)
+	assert.Equals(t, LossMeanSquaredError(expected, actual), 0.625)
+	assert.Equals(t, LossMeanAbsoluteError(expected, actual), 0.75)
+
+	// softmax of (0.5, 1) gives the first class e^0.5 / (e^0.5 + e^1)
+	p := math.Exp(0.5) / (math.Exp(0.5) + math.Exp(1))
+	assert.True(t, math.Abs(LossCrossEntropy(expected, actual)+math.Log(p)) < 1e-12)
+	assert.True(t, math.Abs(LossCrossEntropy([]float64{1}, []float64{0.25})+math.Log(0.25)) < 1e-12)
+	assert.True(t, LossCrossEntropy([]float64{0}, []float64{0}) < 1e-9)
+
+	assert.Equals(t, LossHinge([]float64{1}, []float64{0.25}), 0.75)
+	assert.Equals(t, LossHinge([]float64{-1}, []float64{-2}), 0.0)
+	assert.Equals(t, LossHinge(expected, actual), 1.5)
+
+	classificationError := LossClassificationError(0.5)
+	assert.Equals(t, classificationError(expected, actual), 1.0)
+	assert.Equals(t, classificationError(expected, []float64{0.9, 0.1}), 0.0)
+	assert.Equals(t, classificationError([]float64{1}, []float64{0.7}), 0.0)
+	assert.Equals(t, LossClassificationError(0)([]float64{-1}, []float64{0.2}), 1.0)
+
+}
+
+func TestTrainingSampleScapeLossFunction(t *testing.T) {
+
+	// the single neuron cortex adds 1 to its input
+	examples := []*ng.TrainingSample{
+		{SampleInputs: [][]float64{{0}}, ExpectedOutputs: [][]float64{{1}}},
+		{SampleInputs: [][]float64{{1}}, ExpectedOutputs: [][]float64{{1}}},
+	}
+	cortex := SingleNeuronCortex("cortex")
+
+	scape := NewTrainingSampleScape(examples)
+	scape.UseCompiledCortex = true
+	scape.LossFunction = LossMeanAbsoluteError
+	assert.Equals(t, scape.MeanLoss(cortex), 0.5)
+	assert.Equals(t, scape.Fitness(cortex), 2.0)
+
+	scape.SampleWeights = []float64{3, 1}
+	assert.Equals(t, scape.MeanLoss(cortex), 0.25)
+	assert.Equals(t, scape.Fitness(cortex), 4.0)
+
+	scape.SampleWeights = []float64{1, 0}
+	assert.Equals(t, scape.Fitness(cortex), MAX_FITNESS)
+
+	scape.SampleWeights = []float64{0, 0}
+	assert.Equals(t, scape.MeanLoss(cortex), 0.0)
+
+}
@@ -8,9 +8,12 @@ import (
 
 const DEFAULT_STD_DEVIATION = 1.5
 
-func perturbParameter(parameter float64, saturationBounds []float64) float64 {
+// Default maximum magnitude of a uniform parameter perturbation
+const DEFAULT_STEP_SIZE = 2 * math.Pi
 
-	parameter += ng.RandomInRange(-2*math.Pi, 2*math.Pi)
+func perturbParameter(parameter float64, stepSize float64, saturationBounds []float64) float64 {
+
+	parameter += ng.RandomInRange(-stepSize, stepSize)
 	return saturate(parameter, saturationBounds)
 
 }
@@ -0,0 +1,189 @@
+package neurvolve
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sync"
+	"time"
+)
+
+const DEFAULT_METRICS_CAPACITY = 1000
+
+// Summary statistics of a population after its fitness was computed
+type GenerationStats struct {
+	Generation     int
+	Timestamp      time.Time
+	PopulationSize int
+
+	BestFitness   float64
+	MeanFitness   float64
+	MedianFitness float64
+	FitnessStdDev float64
+
+	// Fraction of the population with a distinct topology, where the
+	// topology is approximated by the number of neurons and connections
+	Diversity float64
+
+	MeanNeurons     float64
+	MeanConnections float64
+	BestNeurons     int
+	BestConnections int
+
+	// Fitness of the fittest cortex against the trainer's ValidationScape,
+	// or nil if it doesn't have one
+	ValidationFitness *float64
+}
+
+func ComputeGenerationStats(generation int, population []EvaluatedCortex) GenerationStats {
+
+	stats := GenerationStats{
+		Generation:     generation,
+		Timestamp:      time.Now(),
+		PopulationSize: len(population),
+	}
+	if len(population) == 0 {
+		return stats
+	}
+
+	fitnessScores := make([]float64, len(population))
+	topologies := make(map[string]bool)
+	totalNeurons := 0
+	totalConnections := 0
+	best := population[0]
+
+	for i, evaldCortex := range population {
+		fitnessScores[i] = evaldCortex.Fitness
+		if evaldCortex.Fitness > best.Fitness {
+			best = evaldCortex
+		}
+		numNeurons := len(evaldCortex.Cortex.Neurons)
+		numConnections := NumConnections(evaldCortex.Cortex)
+		totalNeurons += numNeurons
+		totalConnections += numConnections
+		topologies[fmt.Sprintf("%d/%d", numNeurons, numConnections)] = true
+	}
+
+	size := float64(len(population))
+	stats.BestFitness = best.Fitness
+	stats.MeanFitness = AggregateMean(fitnessScores)
+	stats.MedianFitness = AggregateMedian(fitnessScores)
+	stats.FitnessStdDev = standardDeviation(fitnessScores)
+	stats.Diversity = float64(len(topologies)) / size
+	stats.MeanNeurons = float64(totalNeurons) / size
+	stats.MeanConnections = float64(totalConnections) / size
+	stats.BestNeurons = len(best.Cortex.Neurons)
+	stats.BestConnections = NumConnections(best.Cortex)
+
+	return stats
+
+}
+
+// Ring buffer holding the stats of the most recent generations.
+// Safe for concurrent use by the trainer and http handlers.
+type MetricsHistory struct {
+	mutex    sync.RWMutex
+	stats    []GenerationStats
+	capacity int
+	next     int
+}
+
+func NewMetricsHistory(capacity int) *MetricsHistory {
+	if capacity <= 0 {
+		capacity = DEFAULT_METRICS_CAPACITY
+	}
+	return &MetricsHistory{
+		stats:    make([]GenerationStats, 0, capacity),
+		capacity: capacity,
+	}
+}
+
+func (history *MetricsHistory) Add(stats GenerationStats) {
+	history.mutex.Lock()
+	defer history.mutex.Unlock()
+	if len(history.stats) < history.capacity {
+		history.stats = append(history.stats, stats)
+	} else {
+		history.stats[history.next] = stats
+	}
+	history.next = (history.next + 1) % history.capacity
+}
+
+// All of the retained stats, oldest first
+func (history *MetricsHistory) All() []GenerationStats {
+	history.mutex.RLock()
+	defer history.mutex.RUnlock()
+	all := make([]GenerationStats, 0, len(history.stats))
+	if len(history.stats) < history.capacity {
+		return append(all, history.stats...)
+	}
+	all = append(all, history.stats[history.next:]...)
+	return append(all, history.stats[:history.next]...)
+}
+
+func (history *MetricsHistory) Latest() (stats GenerationStats, ok bool) {
+	history.mutex.RLock()
+	defer history.mutex.RUnlock()
+	if len(history.stats) == 0 {
+		return
+	}
+	latest := (history.next - 1 + history.capacity) % history.capacity
+	return history.stats[latest], true
+}
+
+// Write the latest stats as gauges in the Prometheus text exposition format
+func (history *MetricsHistory) WritePrometheus(w io.Writer) error {
+
+	stats, ok := history.Latest()
+	if !ok {
+		return nil
+	}
+
+	gauges := []struct {
+		name  string
+		help  string
+		value float64
+	}{
+		{"neurvolve_generation", "Most recently evaluated generation", float64(stats.Generation)},
+		{"neurvolve_population_size", "Number of cortexes in the population", float64(stats.PopulationSize)},
+		{"neurvolve_fitness_best", "Fitness of the fittest cortex", stats.BestFitness},
+		{"neurvolve_fitness_mean", "Mean fitness of the population", stats.MeanFitness},
+		{"neurvolve_fitness_median", "Median fitness of the population", stats.MedianFitness},
+		{"neurvolve_fitness_stddev", "Standard deviation of the population fitness", stats.FitnessStdDev},
+		{"neurvolve_diversity", "Fraction of the population with a distinct topology", stats.Diversity},
+		{"neurvolve_neurons_mean", "Mean number of neurons per cortex", stats.MeanNeurons},
+		{"neurvolve_connections_mean", "Mean number of connections per cortex", stats.MeanConnections},
+		{"neurvolve_neurons_best", "Number of neurons in the fittest cortex", float64(stats.BestNeurons)},
+		{"neurvolve_connections_best", "Number of connections in the fittest cortex", float64(stats.BestConnections)},
+	}
+
+	if stats.ValidationFitness != nil {
+		gauges = append(gauges, struct {
+			name  string
+			help  string
+			value float64
+		}{"neurvolve_fitness_validation", "Fitness of the fittest cortex on the validation set", *stats.ValidationFitness})
+	}
+
+	for _, gauge := range gauges {
+		_, err := fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s gauge\n%s %v\n",
+			gauge.name, gauge.help, gauge.name, gauge.name, gauge.value)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+
+}
+
+func standardDeviation(values []float64) float64 {
+	if len(values) == 0 {
+		return 0.0
+	}
+	mean := AggregateMean(values)
+	sumSquares := 0.0
+	for _, value := range values {
+		sumSquares += (value - mean) * (value - mean)
+	}
+	return math.Sqrt(sumSquares / float64(len(values)))
+}
@@ -0,0 +1,100 @@
+package neurvolve
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/couchbaselabs/go.assert"
+	ng "github.com/maxxk/neurgo"
+	"math"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestMetricsHistoryRingBuffer(t *testing.T) {
+
+	history := NewMetricsHistory(3)
+
+	_, ok := history.Latest()
+	assert.False(t, ok)
+
+	for i := 0; i < 5; i++ {
+		history.Add(GenerationStats{Generation: i})
+	}
+
+	all := history.All()
+	assert.Equals(t, len(all), 3)
+	assert.Equals(t, all[0].Generation, 2)
+	assert.Equals(t, all[2].Generation, 4)
+
+	latest, ok := history.Latest()
+	assert.True(t, ok)
+	assert.Equals(t, latest.Generation, 4)
+
+}
+
+func TestComputeGenerationStats(t *testing.T) {
+
+	population := []EvaluatedCortex{
+		{Cortex: SingleNeuronCortex("c1"), Fitness: 3.0},
+		{Cortex: SingleNeuronCortex("c2"), Fitness: 1.0},
+		{Cortex: BasicCortex(), Fitness: 2.0},
+		{Cortex: SingleNeuronCortex("c3"), Fitness: 6.0},
+	}
+
+	stats := ComputeGenerationStats(7, population)
+	assert.Equals(t, stats.Generation, 7)
+	assert.Equals(t, stats.PopulationSize, 4)
+	assert.Equals(t, stats.BestFitness, 6.0)
+	assert.Equals(t, stats.MeanFitness, 3.0)
+	assert.Equals(t, stats.MedianFitness, 2.5)
+	assert.Equals(t, stats.BestNeurons, 1)
+	assert.Equals(t, stats.Diversity, 0.5)
+
+}
+
+func TestWritePrometheus(t *testing.T) {
+
+	history := NewMetricsHistory(10)
+	history.Add(GenerationStats{Generation: 12, BestFitness: 0.5})
+
+	buffer := &bytes.Buffer{}
+	err := history.WritePrometheus(buffer)
+	assert.True(t, err == nil)
+
+	output := buffer.String()
+	assert.True(t, strings.Contains(output, "# TYPE neurvolve_generation gauge\n"))
+	assert.True(t, strings.Contains(output, "\nneurvolve_generation 12\n"))
+	assert.True(t, strings.Contains(output, "\nneurvolve_fitness_best 0.5\n"))
+
+}
+
+func TestNonFiniteFitnessCanBeEncoded(t *testing.T) {
+
+	for _, fitness := range []float64{math.Inf(1), math.Inf(-1), math.NaN()} {
+
+		pt := &PopulationTrainer{
+			FitnessThreshold: math.Inf(1),
+			MaxGenerations:   1,
+			CortexMutator:    NoOpMutator,
+			Snapshots:        NewSnapshotStore(),
+			Metrics:          NewMetricsHistory(10),
+			Events:           NewEventBroadcaster(10),
+		}
+		events := pt.Events.Subscribe()
+		population := []*ng.Cortex{SingleNeuronCortex("cortex1"), SingleNeuronCortex("cortex2")}
+		pt.Train(population, ConstantScape{fitness}, NewNullRecorder())
+
+		_, err := json.Marshal(pt.Metrics.All())
+		assert.True(t, err == nil)
+		_, err = json.Marshal(pt.Snapshots.Latest())
+		assert.True(t, err == nil)
+		err = writeServerSentEvent(httptest.NewRecorder(), <-events)
+		assert.True(t, err == nil)
+
+		stats, _ := pt.Metrics.Latest()
+		assert.True(t, math.Abs(stats.BestFitness) == MAX_FITNESS)
+
+	}
+
+}
@@ -503,7 +503,7 @@ func NeuronMutateWeights(neuron *ng.Neuron) (bool, MutateResult) {
 	probability := parameterPerturbProbability(neuron)
 	for _, cxn := range neuron.Inbound {
 		saturationBounds := []float64{-100000, 100000}
-		didPerturbWeight := possiblyPerturbConnection(cxn, probability, saturationBounds)
+		didPerturbWeight := possiblyPerturbConnection(cxn, probability, DEFAULT_STEP_SIZE, saturationBounds)
 		if didPerturbWeight == true {
 			didPerturbAnyWeights = true
 		}
@@ -678,3 +678,24 @@ func TopologyOrWeightMutator(cortex *ng.Cortex) (success bool, result MutateResu
 	result = "nothing"
 	return
 }
+
+// Returns a mutator which applies one of the given mutators, chosen at
+// random, retrying with another one if the chosen mutator fails.
+func CombinedCortexMutator(mutators ...CortexMutator) CortexMutator {
+	return func(cortex *ng.Cortex) (success bool, result MutateResult) {
+		// before we mutate the cortex, we need to init it,
+		// otherwise things like Outsplice will fail because
+		// there are no DataChan's.
+		cortex.Init()
+		for i := 0; i <= 100; i++ {
+			randInt := RandomIntInRange(0, len(mutators))
+			mutator := mutators[randInt]
+			success, result = mutator(cortex)
+			if success {
+				return
+			}
+			logg.LogTo("NEURVOLVE", "Mutate didn't work, retrying...")
+		}
+		return
+	}
+}
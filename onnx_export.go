@@ -0,0 +1,301 @@
+package neurvolve
+
+import (
+	"encoding/binary"
+	"fmt"
+	ng "github.com/maxxk/neurgo"
+	"io"
+	"math"
+)
+
+// The exported models only use ops from the default domain which have
+// not changed since this opset
+const (
+	ONNX_IR_VERSION    = 7
+	ONNX_OPSET_VERSION = 13
+)
+
+// ONNX TensorProto.DataType and AttributeProto.AttributeType values
+const (
+	onnxDataTypeFloat     = 1
+	onnxAttributeTypeInt  = 2
+	onnxBatchDimParameter = "N"
+)
+
+// ONNX op for each supported activation function, keyed by the name
+// of the neurgo EncodableActivation
+var onnxActivationOps = map[string]string{
+	ng.EncodableSigmoid().Name:  "Sigmoid",
+	ng.EncodableTanh().Name:     "Tanh",
+	ng.EncodableIdentity().Name: "Identity",
+}
+
+// The subset of the ONNX protobuf messages needed to describe a cortex
+type onnxModel struct {
+	irVersion    int64
+	opsetVersion int64
+	producerName string
+	graph        onnxGraph
+}
+
+type onnxGraph struct {
+	name         string
+	nodes        []onnxNode
+	initializers []onnxTensor
+	inputs       []onnxValueInfo
+	outputs      []onnxValueInfo
+}
+
+type onnxNode struct {
+	name       string
+	opType     string
+	inputs     []string
+	outputs    []string
+	attributes []onnxAttribute
+}
+
+// Only int attributes are needed, for the axis of Concat
+type onnxAttribute struct {
+	name string
+	i    int64
+}
+
+type onnxTensor struct {
+	name      string
+	dims      []int64
+	floatData []float32
+}
+
+// A float tensor of shape [N, size], where N is the batch size
+type onnxValueInfo struct {
+	name string
+	size int64
+}
+
+// Write the (non-recurrent) cortex as an ONNX model which only uses
+// standard ops: Concat, MatMul, Add and the activation functions.
+// There is one graph input per sensor and one graph output per
+// actuator, named after their uuids, with shape [N, vector length].
+// Weights are stored as 32 bit floats.
+func ExportOnnx(cortex *ng.Cortex, w io.Writer) error {
+	model, err := onnxModelForCortex(cortex)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(model.marshal())
+	return err
+}
+
+func onnxModelForCortex(cortex *ng.Cortex) (*onnxModel, error) {
+
+	graph := onnxGraph{name: cortex.NodeId.UUID}
+
+	// length of the vector each node outputs, to check the weights
+	// of the neurons connected to it
+	vectorLengths := make(map[string]int)
+
+	for _, sensor := range cortex.Sensors {
+		vectorLengths[sensor.NodeId.UUID] = sensor.VectorLength
+		graph.inputs = append(graph.inputs, onnxValueInfo{
+			name: sensor.NodeId.UUID,
+			size: int64(sensor.VectorLength),
+		})
+	}
+
+	neurons, err := topologicalOrder(cortex)
+	if err != nil {
+		return nil, err
+	}
+
+	isNeuron := make(map[string]bool)
+	for _, neuron := range neurons {
+
+		uuid := neuron.NodeId.UUID
+		if neuron.ActivationFunction == nil {
+			return nil, fmt.Errorf("Neuron %v has no activation function", uuid)
+		}
+		activationOp, ok := onnxActivationOps[neuron.ActivationFunction.Name]
+		if !ok {
+			return nil, fmt.Errorf("Cannot export activation function %q of neuron %v", neuron.ActivationFunction.Name, uuid)
+		}
+
+		inputNames := make([]string, 0)
+		weights := make([]float32, 0)
+		for _, inbound := range neuron.Inbound {
+			vectorLength, ok := vectorLengths[inbound.NodeId.UUID]
+			if !ok {
+				return nil, fmt.Errorf("Neuron %v has an inbound connection from unknown node %v", uuid, inbound.NodeId.UUID)
+			}
+			if len(inbound.Weights) != vectorLength {
+				return nil, fmt.Errorf("Neuron %v has %d weights for %v, expected %d", uuid, len(inbound.Weights), inbound.NodeId.UUID, vectorLength)
+			}
+			inputNames = append(inputNames, inbound.NodeId.UUID)
+			for _, weight := range inbound.Weights {
+				weights = append(weights, float32(weight))
+			}
+		}
+
+		// concatenate all inbound vectors into a single [N, k] input
+		input := uuid + "/input"
+		graph.nodes = append(graph.nodes, onnxConcat(input, inputNames))
+
+		weightsName := uuid + "/weights"
+		biasName := uuid + "/bias"
+		graph.initializers = append(graph.initializers,
+			onnxTensor{name: weightsName, dims: []int64{int64(len(weights)), 1}, floatData: weights},
+			onnxTensor{name: biasName, dims: []int64{1}, floatData: []float32{float32(neuron.Bias)}},
+		)
+
+		weightedSum := uuid + "/weighted_sum"
+		sum := uuid + "/sum"
+		graph.nodes = append(graph.nodes,
+			onnxNode{name: weightedSum, opType: "MatMul", inputs: []string{input, weightsName}, outputs: []string{weightedSum}},
+			onnxNode{name: sum, opType: "Add", inputs: []string{weightedSum, biasName}, outputs: []string{sum}},
+			onnxNode{name: uuid, opType: activationOp, inputs: []string{sum}, outputs: []string{uuid}},
+		)
+		isNeuron[uuid] = true
+		vectorLengths[uuid] = 1
+
+	}
+
+	for _, actuator := range cortex.Actuators {
+		inputNames := make([]string, 0)
+		for _, inbound := range actuator.Inbound {
+			if !isNeuron[inbound.NodeId.UUID] {
+				return nil, fmt.Errorf("Actuator %v has an inbound connection from unknown neuron %v", actuator.NodeId.UUID, inbound.NodeId.UUID)
+			}
+			inputNames = append(inputNames, inbound.NodeId.UUID)
+		}
+		graph.nodes = append(graph.nodes, onnxConcat(actuator.NodeId.UUID, inputNames))
+		graph.outputs = append(graph.outputs, onnxValueInfo{
+			name: actuator.NodeId.UUID,
+			size: int64(len(inputNames)),
+		})
+	}
+
+	model := &onnxModel{
+		irVersion:    ONNX_IR_VERSION,
+		opsetVersion: ONNX_OPSET_VERSION,
+		producerName: "neurvolve",
+		graph:        graph,
+	}
+	return model, nil
+
+}
+
+func onnxConcat(output string, inputs []string) onnxNode {
+	return onnxNode{
+		name:       output,
+		opType:     "Concat",
+		inputs:     inputs,
+		outputs:    []string{output},
+		attributes: []onnxAttribute{{name: "axis", i: 1}},
+	}
+}
+
+// Protobuf encoding, using the field numbers from onnx.proto
+
+func (model *onnxModel) marshal() []byte {
+	b := appendVarintField(nil, 1, uint64(model.irVersion))
+	b = appendBytesField(b, 2, []byte(model.producerName))
+	b = appendBytesField(b, 7, model.graph.marshal())
+	opset := appendBytesField(nil, 1, []byte(""))
+	opset = appendVarintField(opset, 2, uint64(model.opsetVersion))
+	b = appendBytesField(b, 8, opset)
+	return b
+}
+
+func (graph *onnxGraph) marshal() []byte {
+	var b []byte
+	for _, node := range graph.nodes {
+		b = appendBytesField(b, 1, node.marshal())
+	}
+	b = appendBytesField(b, 2, []byte(graph.name))
+	for _, tensor := range graph.initializers {
+		b = appendBytesField(b, 5, tensor.marshal())
+	}
+	for _, input := range graph.inputs {
+		b = appendBytesField(b, 11, input.marshal())
+	}
+	for _, output := range graph.outputs {
+		b = appendBytesField(b, 12, output.marshal())
+	}
+	return b
+}
+
+func (node *onnxNode) marshal() []byte {
+	var b []byte
+	for _, input := range node.inputs {
+		b = appendBytesField(b, 1, []byte(input))
+	}
+	for _, output := range node.outputs {
+		b = appendBytesField(b, 2, []byte(output))
+	}
+	b = appendBytesField(b, 3, []byte(node.name))
+	b = appendBytesField(b, 4, []byte(node.opType))
+	for _, attribute := range node.attributes {
+		b = appendBytesField(b, 5, attribute.marshal())
+	}
+	return b
+}
+
+func (attribute *onnxAttribute) marshal() []byte {
+	b := appendBytesField(nil, 1, []byte(attribute.name))
+	b = appendVarintField(b, 3, uint64(attribute.i))
+	b = appendVarintField(b, 20, onnxAttributeTypeInt)
+	return b
+}
+
+func (tensor *onnxTensor) marshal() []byte {
+	var b []byte
+	for _, dim := range tensor.dims {
+		b = appendVarintField(b, 1, uint64(dim))
+	}
+	b = appendVarintField(b, 2, onnxDataTypeFloat)
+	packed := make([]byte, 4*len(tensor.floatData))
+	for i, value := range tensor.floatData {
+		binary.LittleEndian.PutUint32(packed[4*i:], math.Float32bits(value))
+	}
+	b = appendBytesField(b, 4, packed)
+	b = appendBytesField(b, 8, []byte(tensor.name))
+	return b
+}
+
+func (valueInfo *onnxValueInfo) marshal() []byte {
+	batchDim := appendBytesField(nil, 2, []byte(onnxBatchDimParameter))
+	sizeDim := appendVarintField(nil, 1, uint64(valueInfo.size))
+	shape := appendBytesField(nil, 1, batchDim)
+	shape = appendBytesField(shape, 1, sizeDim)
+	tensorType := appendVarintField(nil, 1, onnxDataTypeFloat)
+	tensorType = appendBytesField(tensorType, 2, shape)
+	typeProto := appendBytesField(nil, 1, tensorType)
+	b := appendBytesField(nil, 1, []byte(valueInfo.name))
+	b = appendBytesField(b, 2, typeProto)
+	return b
+}
+
+const (
+	protoWireVarint = 0
+	protoWire64Bit  = 1
+	protoWireBytes  = 2
+	protoWire32Bit  = 5
+)
+
+func appendVarint(b []byte, value uint64) []byte {
+	for value >= 0x80 {
+		b = append(b, byte(value)|0x80)
+		value >>= 7
+	}
+	return append(b, byte(value))
+}
+
+func appendVarintField(b []byte, field int, value uint64) []byte {
+	b = appendVarint(b, uint64(field)<<3|protoWireVarint)
+	return appendVarint(b, value)
+}
+
+func appendBytesField(b []byte, field int, value []byte) []byte {
+	b = appendVarint(b, uint64(field)<<3|protoWireBytes)
+	b = appendVarint(b, uint64(len(value)))
+	return append(b, value...)
+}
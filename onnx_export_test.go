@@ -0,0 +1,94 @@
+package neurvolve
+
+import (
+	"bytes"
+	"github.com/couchbaselabs/go.assert"
+	ng "github.com/maxxk/neurgo"
+	"math"
+	"testing"
+)
+
+// Weights are exported as 32 bit floats
+const onnxTolerance = 1e-5
+
+func TestExportOnnxRoundTrip(t *testing.T) {
+
+	examples := ng.XnorTrainingSamples()
+
+	cortex := BasicCortex()
+	PerturbParameters(cortex, []float64{-10 * math.Pi, 10 * math.Pi})
+	for _, cortex := range []*ng.Cortex{BasicCortex(), ng.XnorCortex(), cortex} {
+
+		model := exportAndReadOnnx(t, cortex)
+
+		expectedOutputs := CortexOutputs(cortex, examples)
+		for i, example := range examples {
+			outputs, err := model.Compute(example.SampleInputs)
+			assert.True(t, err == nil)
+			assertVectorsClose(t, outputs, expectedOutputs[i])
+		}
+
+	}
+
+}
+
+func TestExportOnnxMatchesCompiledCortex(t *testing.T) {
+
+	inputs := [][][]float64{{{-1, -1}}, {{-1, 1}}, {{0.25, 1}}}
+	for _, cortex := range []*ng.Cortex{BasicCortex(), SingleNeuronCortex("cortex")} {
+
+		model := exportAndReadOnnx(t, cortex)
+		assert.Equals(t, len(model.InputNames()), len(cortex.Sensors))
+		assert.Equals(t, model.InputNames()[0], cortex.Sensors[0].NodeId.UUID)
+		assert.Equals(t, model.OutputNames()[0], cortex.Actuators[0].NodeId.UUID)
+
+		compiled, err := CompileCortex(cortex)
+		assert.True(t, err == nil)
+
+		for _, input := range inputs {
+			input[0] = input[0][:cortex.Sensors[0].VectorLength]
+			outputs, err := model.Compute(input)
+			assert.True(t, err == nil)
+			assertVectorsClose(t, outputs, compiled.Compute(input))
+		}
+
+	}
+
+}
+
+func TestExportOnnxRecurrent(t *testing.T) {
+
+	err := ExportOnnx(BasicCortexRecurrent(), &bytes.Buffer{})
+	assert.True(t, err != nil)
+
+}
+
+func TestExportOnnxWeightMismatch(t *testing.T) {
+
+	cortex := BasicCortex()
+	inbound := cortex.Neurons[0].Inbound[0]
+	inbound.Weights = append(inbound.Weights, 1)
+
+	err := ExportOnnx(cortex, &bytes.Buffer{})
+	assert.True(t, err != nil)
+
+}
+
+func exportAndReadOnnx(t *testing.T, cortex *ng.Cortex) *OnnxModel {
+	buffer := &bytes.Buffer{}
+	err := ExportOnnx(cortex, buffer)
+	assert.True(t, err == nil)
+	model, err := ReadOnnxModel(buffer)
+	assert.True(t, err == nil)
+	return model
+}
+
+func assertVectorsClose(t *testing.T, actual, expected [][]float64) {
+	assert.Equals(t, len(actual), len(expected))
+	for i := range expected {
+		assert.Equals(t, len(actual[i]), len(expected[i]))
+		for j := range expected[i] {
+			assert.True(t, math.Abs(actual[i][j]-expected[i][j]) < onnxTolerance)
+		}
+	}
+}
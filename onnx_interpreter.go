@@ -0,0 +1,491 @@
+package neurvolve
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+)
+
+// A small interpreter for ONNX models such as the ones written by
+// ExportOnnx.  It supports float tensors of rank 1 and 2 and the ops
+// Concat, MatMul, Add, Sigmoid, Tanh, Relu and Identity, which is
+// enough to check exported cortexes without an ONNX runtime.
+type OnnxModel struct {
+	model *onnxModel
+}
+
+// A dense row-major tensor of rank 1 or 2
+type onnxValue struct {
+	dims []int
+	data []float64
+}
+
+func ReadOnnxModel(r io.Reader) (*OnnxModel, error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	model := &onnxModel{}
+	if err := model.unmarshal(data); err != nil {
+		return nil, err
+	}
+	return &OnnxModel{model: model}, nil
+}
+
+// Names of the graph inputs, in order
+func (onnx *OnnxModel) InputNames() []string {
+	return valueInfoNames(onnx.model.graph.inputs)
+}
+
+// Names of the graph outputs, in order
+func (onnx *OnnxModel) OutputNames() []string {
+	return valueInfoNames(onnx.model.graph.outputs)
+}
+
+// Evaluate the graph for a single sample, with one vector per graph
+// input and one vector per graph output, which is the layout of
+// ng.TrainingSample for exported cortexes.
+func (onnx *OnnxModel) Compute(inputs [][]float64) ([][]float64, error) {
+
+	graph := onnx.model.graph
+	if len(inputs) != len(graph.inputs) {
+		return nil, fmt.Errorf("Expected %d inputs, got %d", len(graph.inputs), len(inputs))
+	}
+
+	values := make(map[string]onnxValue)
+	for _, tensor := range graph.initializers {
+		value, err := tensor.value()
+		if err != nil {
+			return nil, err
+		}
+		values[tensor.name] = value
+	}
+	for i, input := range graph.inputs {
+		if int64(len(inputs[i])) != input.size {
+			return nil, fmt.Errorf("Expected input %v to have length %d, got %d", input.name, input.size, len(inputs[i]))
+		}
+		values[input.name] = onnxValue{dims: []int{1, len(inputs[i])}, data: inputs[i]}
+	}
+
+	for _, node := range graph.nodes {
+		nodeInputs := make([]onnxValue, len(node.inputs))
+		for i, name := range node.inputs {
+			value, ok := values[name]
+			if !ok {
+				return nil, fmt.Errorf("Node %v: input %v has not been computed", node.name, name)
+			}
+			nodeInputs[i] = value
+		}
+		if len(node.outputs) != 1 {
+			return nil, fmt.Errorf("Node %v: expected one output, got %d", node.name, len(node.outputs))
+		}
+		output, err := evaluateOnnxNode(node, nodeInputs)
+		if err != nil {
+			return nil, fmt.Errorf("Node %v: %v", node.name, err)
+		}
+		values[node.outputs[0]] = output
+	}
+
+	outputs := make([][]float64, len(graph.outputs))
+	for i, output := range graph.outputs {
+		value, ok := values[output.name]
+		if !ok {
+			return nil, fmt.Errorf("Output %v has not been computed", output.name)
+		}
+		outputs[i] = value.data
+	}
+	return outputs, nil
+
+}
+
+func evaluateOnnxNode(node onnxNode, inputs []onnxValue) (onnxValue, error) {
+
+	switch node.opType {
+	case "Concat":
+		return onnxConcatValues(node, inputs)
+	case "MatMul":
+		if len(inputs) != 2 {
+			return onnxValue{}, fmt.Errorf("MatMul expects 2 inputs, got %d", len(inputs))
+		}
+		return onnxMatMul(inputs[0], inputs[1])
+	case "Add":
+		if len(inputs) != 2 {
+			return onnxValue{}, fmt.Errorf("Add expects 2 inputs, got %d", len(inputs))
+		}
+		return onnxAdd(inputs[0], inputs[1])
+	case "Sigmoid":
+		return onnxElementwise(inputs, func(x float64) float64 { return 1.0 / (1.0 + math.Exp(-x)) })
+	case "Tanh":
+		return onnxElementwise(inputs, math.Tanh)
+	case "Relu":
+		return onnxElementwise(inputs, func(x float64) float64 { return math.Max(0, x) })
+	case "Identity":
+		return onnxElementwise(inputs, func(x float64) float64 { return x })
+	}
+	return onnxValue{}, fmt.Errorf("Unsupported op: %v", node.opType)
+
+}
+
+func onnxConcatValues(node onnxNode, inputs []onnxValue) (onnxValue, error) {
+
+	axis := int64(0)
+	for _, attribute := range node.attributes {
+		if attribute.name == "axis" {
+			axis = attribute.i
+		}
+	}
+	if axis != 1 && axis != -1 {
+		return onnxValue{}, fmt.Errorf("Concat is only supported along axis 1, got %d", axis)
+	}
+
+	rows := -1
+	cols := 0
+	for _, input := range inputs {
+		if len(input.dims) != 2 || (rows != -1 && input.dims[0] != rows) {
+			return onnxValue{}, fmt.Errorf("Concat inputs must be matrices with the same number of rows")
+		}
+		rows = input.dims[0]
+		cols += input.dims[1]
+	}
+
+	data := make([]float64, 0, rows*cols)
+	for row := 0; row < rows; row++ {
+		for _, input := range inputs {
+			data = append(data, input.data[row*input.dims[1]:(row+1)*input.dims[1]]...)
+		}
+	}
+	return onnxValue{dims: []int{rows, cols}, data: data}, nil
+
+}
+
+func onnxMatMul(a, b onnxValue) (onnxValue, error) {
+
+	if len(a.dims) != 2 || len(b.dims) != 2 || a.dims[1] != b.dims[0] {
+		return onnxValue{}, fmt.Errorf("Cannot multiply %v by %v", a.dims, b.dims)
+	}
+	rows, inner, cols := a.dims[0], a.dims[1], b.dims[1]
+	data := make([]float64, rows*cols)
+	for i := 0; i < rows; i++ {
+		for j := 0; j < cols; j++ {
+			sum := 0.0
+			for k := 0; k < inner; k++ {
+				sum += a.data[i*inner+k] * b.data[k*cols+j]
+			}
+			data[i*cols+j] = sum
+		}
+	}
+	return onnxValue{dims: []int{rows, cols}, data: data}, nil
+
+}
+
+// Add with numpy style broadcasting, for tensors of rank up to 2
+func onnxAdd(a, b onnxValue) (onnxValue, error) {
+
+	aDims := a.matrixDims()
+	bDims := b.matrixDims()
+	dims := make([]int, 2)
+	for i := range dims {
+		switch {
+		case aDims[i] == bDims[i] || bDims[i] == 1:
+			dims[i] = aDims[i]
+		case aDims[i] == 1:
+			dims[i] = bDims[i]
+		default:
+			return onnxValue{}, fmt.Errorf("Cannot broadcast %v and %v", a.dims, b.dims)
+		}
+	}
+
+	data := make([]float64, dims[0]*dims[1])
+	for i := 0; i < dims[0]; i++ {
+		for j := 0; j < dims[1]; j++ {
+			data[i*dims[1]+j] = a.broadcastAt(aDims, i, j) + b.broadcastAt(bDims, i, j)
+		}
+	}
+	if len(a.dims) < 2 && len(b.dims) < 2 {
+		return onnxValue{dims: []int{dims[1]}, data: data}, nil
+	}
+	return onnxValue{dims: dims, data: data}, nil
+
+}
+
+func onnxElementwise(inputs []onnxValue, f func(float64) float64) (onnxValue, error) {
+	if len(inputs) != 1 {
+		return onnxValue{}, fmt.Errorf("Expected 1 input, got %d", len(inputs))
+	}
+	input := inputs[0]
+	data := make([]float64, len(input.data))
+	for i, x := range input.data {
+		data[i] = f(x)
+	}
+	return onnxValue{dims: input.dims, data: data}, nil
+}
+
+// The dims of the value as a matrix, treating vectors as a single row
+func (value onnxValue) matrixDims() []int {
+	switch len(value.dims) {
+	case 0:
+		return []int{1, 1}
+	case 1:
+		return []int{1, value.dims[0]}
+	}
+	return value.dims
+}
+
+func (value onnxValue) broadcastAt(dims []int, i, j int) float64 {
+	if dims[0] == 1 {
+		i = 0
+	}
+	if dims[1] == 1 {
+		j = 0
+	}
+	return value.data[i*dims[1]+j]
+}
+
+func (tensor *onnxTensor) value() (onnxValue, error) {
+	dims := make([]int, len(tensor.dims))
+	size := 1
+	for i, dim := range tensor.dims {
+		dims[i] = int(dim)
+		size *= int(dim)
+	}
+	if len(dims) > 2 {
+		return onnxValue{}, fmt.Errorf("Tensor %v has unsupported rank %d", tensor.name, len(dims))
+	}
+	if size != len(tensor.floatData) {
+		return onnxValue{}, fmt.Errorf("Tensor %v has %d values, expected %d", tensor.name, len(tensor.floatData), size)
+	}
+	data := make([]float64, size)
+	for i, x := range tensor.floatData {
+		data[i] = float64(x)
+	}
+	return onnxValue{dims: dims, data: data}, nil
+}
+
+func valueInfoNames(valueInfos []onnxValueInfo) []string {
+	names := make([]string, len(valueInfos))
+	for i, valueInfo := range valueInfos {
+		names[i] = valueInfo.name
+	}
+	return names
+}
+
+// Protobuf decoding.  Fields which the interpreter doesn't need are skipped.
+
+func (model *onnxModel) unmarshal(data []byte) error {
+	return parseProto(data, func(field int, varint uint64, bytes []byte) error {
+		switch field {
+		case 1:
+			model.irVersion = int64(varint)
+		case 2:
+			model.producerName = string(bytes)
+		case 7:
+			return model.graph.unmarshal(bytes)
+		case 8:
+			return parseProto(bytes, func(field int, varint uint64, bytes []byte) error {
+				if field == 2 {
+					model.opsetVersion = int64(varint)
+				}
+				return nil
+			})
+		}
+		return nil
+	})
+}
+
+func (graph *onnxGraph) unmarshal(data []byte) error {
+	return parseProto(data, func(field int, varint uint64, bytes []byte) error {
+		switch field {
+		case 1:
+			node := onnxNode{}
+			if err := node.unmarshal(bytes); err != nil {
+				return err
+			}
+			graph.nodes = append(graph.nodes, node)
+		case 2:
+			graph.name = string(bytes)
+		case 5:
+			tensor := onnxTensor{}
+			if err := tensor.unmarshal(bytes); err != nil {
+				return err
+			}
+			graph.initializers = append(graph.initializers, tensor)
+		case 11, 12:
+			valueInfo := onnxValueInfo{}
+			if err := valueInfo.unmarshal(bytes); err != nil {
+				return err
+			}
+			if field == 11 {
+				graph.inputs = append(graph.inputs, valueInfo)
+			} else {
+				graph.outputs = append(graph.outputs, valueInfo)
+			}
+		}
+		return nil
+	})
+}
+
+func (node *onnxNode) unmarshal(data []byte) error {
+	return parseProto(data, func(field int, varint uint64, bytes []byte) error {
+		switch field {
+		case 1:
+			node.inputs = append(node.inputs, string(bytes))
+		case 2:
+			node.outputs = append(node.outputs, string(bytes))
+		case 3:
+			node.name = string(bytes)
+		case 4:
+			node.opType = string(bytes)
+		case 5:
+			attribute := onnxAttribute{}
+			err := parseProto(bytes, func(field int, varint uint64, bytes []byte) error {
+				switch field {
+				case 1:
+					attribute.name = string(bytes)
+				case 3:
+					attribute.i = int64(varint)
+				}
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+			node.attributes = append(node.attributes, attribute)
+		}
+		return nil
+	})
+}
+
+func (tensor *onnxTensor) unmarshal(data []byte) error {
+	return parseProto(data, func(field int, varint uint64, bytes []byte) error {
+		switch field {
+		case 1:
+			if bytes == nil {
+				tensor.dims = append(tensor.dims, int64(varint))
+				return nil
+			}
+			// packed
+			for len(bytes) > 0 {
+				dim, n := binary.Uvarint(bytes)
+				if n <= 0 {
+					return fmt.Errorf("Invalid packed dims in tensor")
+				}
+				tensor.dims = append(tensor.dims, int64(dim))
+				bytes = bytes[n:]
+			}
+		case 2:
+			if varint != onnxDataTypeFloat {
+				return fmt.Errorf("Unsupported tensor data type: %d", varint)
+			}
+		case 4:
+			if bytes == nil {
+				tensor.floatData = append(tensor.floatData, math.Float32frombits(uint32(varint)))
+				return nil
+			}
+			tensor.floatData = append(tensor.floatData, decodeFloat32s(bytes)...)
+		case 8:
+			tensor.name = string(bytes)
+		case 9:
+			// raw_data, as written by most other exporters
+			tensor.floatData = append(tensor.floatData, decodeFloat32s(bytes)...)
+		}
+		return nil
+	})
+}
+
+func (valueInfo *onnxValueInfo) unmarshal(data []byte) error {
+	return parseProto(data, func(field int, varint uint64, bytes []byte) error {
+		switch field {
+		case 1:
+			valueInfo.name = string(bytes)
+		case 2:
+			// TypeProto.tensor_type.shape.dim, the size is the last dim
+			return parseNested(bytes, []int{1, 2, 1}, func(dim []byte) error {
+				return parseProto(dim, func(field int, varint uint64, bytes []byte) error {
+					if field == 1 {
+						valueInfo.size = int64(varint)
+					}
+					return nil
+				})
+			})
+		}
+		return nil
+	})
+}
+
+// Call f with each embedded message found by following the field path
+func parseNested(data []byte, path []int, f func([]byte) error) error {
+	if len(path) == 0 {
+		return f(data)
+	}
+	return parseProto(data, func(field int, varint uint64, bytes []byte) error {
+		if field == path[0] && bytes != nil {
+			return parseNested(bytes, path[1:], f)
+		}
+		return nil
+	})
+}
+
+// Call f for every field of the message.  Varint and fixed size fields are
+// passed as varint with nil bytes, length delimited fields as bytes.
+func parseProto(data []byte, f func(field int, varint uint64, bytes []byte) error) error {
+
+	for len(data) > 0 {
+
+		key, n := binary.Uvarint(data)
+		if n <= 0 {
+			return fmt.Errorf("Invalid protobuf field key")
+		}
+		data = data[n:]
+		field := int(key >> 3)
+
+		var varint uint64
+		var bytes []byte
+
+		switch key & 7 {
+		case protoWireVarint:
+			varint, n = binary.Uvarint(data)
+			if n <= 0 {
+				return fmt.Errorf("Invalid varint in field %d", field)
+			}
+			data = data[n:]
+		case protoWire64Bit:
+			if len(data) < 8 {
+				return fmt.Errorf("Truncated field %d", field)
+			}
+			varint = binary.LittleEndian.Uint64(data)
+			data = data[8:]
+		case protoWire32Bit:
+			if len(data) < 4 {
+				return fmt.Errorf("Truncated field %d", field)
+			}
+			varint = uint64(binary.LittleEndian.Uint32(data))
+			data = data[4:]
+		case protoWireBytes:
+			length, n := binary.Uvarint(data)
+			if n <= 0 || uint64(len(data)-n) < length {
+				return fmt.Errorf("Truncated field %d", field)
+			}
+			bytes = data[n : n+int(length)]
+			data = data[n+int(length):]
+		default:
+			return fmt.Errorf("Unsupported wire type %d in field %d", key&7, field)
+		}
+
+		if err := f(field, varint, bytes); err != nil {
+			return err
+		}
+
+	}
+	return nil
+
+}
+
+func decodeFloat32s(data []byte) []float32 {
+	values := make([]float32, len(data)/4)
+	for i := range values {
+		values[i] = math.Float32frombits(binary.LittleEndian.Uint32(data[4*i:]))
+	}
+	return values
+}
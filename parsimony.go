@@ -0,0 +1,265 @@
+package neurvolve
+
+import (
+	"github.com/couchbaselabs/logg"
+	ng "github.com/maxxk/neurgo"
+	"math"
+	"math/rand"
+	"sort"
+)
+
+type ParsimonyScheme int
+
+const (
+	// Survivors are chosen purely by fitness
+	PARSIMONY_NONE ParsimonyScheme = iota
+
+	// Survivors are chosen by fitness rounded down to a multiple of
+	// Tolerance, and cortexes with the same rounded fitness are ranked
+	// by complexity instead
+	PARSIMONY_LEXICOGRAPHIC
+
+	// Each survivor is the winner of a size tournament between the
+	// winners of two fitness tournaments (Luke & Panait)
+	PARSIMONY_DOUBLE_TOURNAMENT
+)
+
+const DEFAULT_FITNESS_TOURNAMENT_SIZE = 3
+const DEFAULT_SIZE_TOURNAMENT_PROBABILITY = 0.7
+
+// Penalizes cortexes for their size, so that topology mutations which
+// don't improve fitness aren't kept around for free.
+type ComplexityPenalty struct {
+	NeuronPenalty     float64 // per neuron
+	ConnectionPenalty float64 // per inbound connection to a neuron
+	WeightPenalty     float64 // per unit of total absolute weight
+}
+
+func (p ComplexityPenalty) Penalty(cortex *ng.Cortex) float64 {
+	penalty := p.NeuronPenalty * float64(len(cortex.Neurons))
+	penalty += p.ConnectionPenalty * float64(NumConnections(cortex))
+	penalty += p.WeightPenalty * TotalWeightMagnitude(cortex)
+	return penalty
+}
+
+// Parsimony pressure applied by the PopulationTrainer
+type Parsimony struct {
+
+	// Subtracted from each cortex's fitness when choosing survivors.  The
+	// fitness which is published and compared with the FitnessThreshold
+	// is still the one given by the scape.
+	Penalty ComplexityPenalty
+
+	// How survivors are chosen when culling
+	Scheme ParsimonyScheme
+
+	// Width of the fitness buckets of PARSIMONY_LEXICOGRAPHIC: cortexes
+	// whose fitness rounds down to the same multiple of Tolerance are
+	// considered equally fit.  These are fixed buckets rather than a
+	// maximum difference, so that the ranking is a consistent ordering:
+	// with a tolerance of 0.1, 1.0 and 1.09 are equally fit, but 0.99
+	// and 1.01 are not.
+	Tolerance float64
+
+	// Number of cortexes competing in each PARSIMONY_DOUBLE_TOURNAMENT
+	// fitness tournament.  Defaults to DEFAULT_FITNESS_TOURNAMENT_SIZE
+	FitnessTournamentSize int
+
+	// Probability that the less complex cortex wins the size tournament.
+	// Defaults to DEFAULT_SIZE_TOURNAMENT_PROBABILITY if nil, so that 0
+	// can be used to never prefer the less complex cortex.
+	SizeTournamentProbability *float64
+}
+
+// The number of neurons plus the number of inbound neuron connections,
+// which is the measure of size used when ranking by parsimony.
+func Complexity(cortex *ng.Cortex) int {
+	return len(cortex.Neurons) + NumConnections(cortex)
+}
+
+func NumConnections(cortex *ng.Cortex) int {
+	numConnections := 0
+	for _, neuron := range cortex.Neurons {
+		numConnections += len(neuron.Inbound)
+	}
+	return numConnections
+}
+
+func TotalWeightMagnitude(cortex *ng.Cortex) float64 {
+	total := 0.0
+	for _, neuron := range cortex.Neurons {
+		for _, cxn := range neuron.Inbound {
+			for _, weight := range cxn.Weights {
+				total += math.Abs(weight)
+			}
+		}
+	}
+	return total
+}
+
+// Choose numSurvivors cortexes from a population which is already sorted
+// by fitness, after subtracting the Penalty from their fitness.  The
+// survivors are returned with their original fitness, sorted by it.
+func (p *Parsimony) selectSurvivors(population []EvaluatedCortex, numSurvivors int) []EvaluatedCortex {
+
+	fitness := make(map[*ng.Cortex]float64)
+	penalized := make([]EvaluatedCortex, len(population))
+	for i, evaldCortex := range population {
+		fitness[evaldCortex.Cortex] = evaldCortex.Fitness
+		evaldCortex.Fitness -= p.Penalty.Penalty(evaldCortex.Cortex)
+		penalized[i] = evaldCortex
+	}
+	sort.Stable(EvaluatedCortexes(penalized))
+
+	survivors := p.selectPenalizedSurvivors(penalized, numSurvivors)
+	for i := range survivors {
+		survivors[i].Fitness = fitness[survivors[i].Cortex]
+	}
+	sort.Stable(EvaluatedCortexes(survivors))
+	return survivors
+
+}
+
+func (p *Parsimony) selectPenalizedSurvivors(population []EvaluatedCortex, numSurvivors int) []EvaluatedCortex {
+
+	switch p.Scheme {
+	case PARSIMONY_NONE:
+		return truncateSelection(population, numSurvivors)
+	case PARSIMONY_LEXICOGRAPHIC:
+		sorted := make([]EvaluatedCortex, len(population))
+		copy(sorted, population)
+		sort.Stable(lexicographicSorter{sorted, p.Tolerance})
+		return truncateSelection(sorted, numSurvivors)
+	case PARSIMONY_DOUBLE_TOURNAMENT:
+		return p.doubleTournamentSelection(population, numSurvivors)
+	default:
+		logg.LogPanic("Unknown parsimony scheme: %v", p.Scheme)
+	}
+	return nil
+
+}
+
+func (p *Parsimony) doubleTournamentSelection(population []EvaluatedCortex, numSurvivors int) []EvaluatedCortex {
+
+	remaining := make([]EvaluatedCortex, len(population))
+	copy(remaining, population)
+
+	survivors := make([]EvaluatedCortex, 0)
+	for len(survivors) < numSurvivors {
+		first := p.fitnessTournament(remaining)
+		second := p.fitnessTournament(remaining)
+		winner := p.sizeTournament(remaining, first, second)
+		survivors = append(survivors, remaining[winner])
+		remaining = append(remaining[:winner], remaining[winner+1:]...)
+	}
+	return survivors
+
+}
+
+// Returns the index of the fittest of several randomly chosen cortexes
+func (p *Parsimony) fitnessTournament(population []EvaluatedCortex) int {
+
+	tournamentSize := p.FitnessTournamentSize
+	if tournamentSize <= 0 {
+		tournamentSize = DEFAULT_FITNESS_TOURNAMENT_SIZE
+	}
+
+	best := RandomIntInRange(0, len(population))
+	for i := 1; i < tournamentSize; i++ {
+		candidate := RandomIntInRange(0, len(population))
+		if population[candidate].Fitness > population[best].Fitness {
+			best = candidate
+		}
+	}
+	return best
+
+}
+
+// Returns the index of the less complex of the two cortexes with
+// probability SizeTournamentProbability, otherwise the more complex one
+func (p *Parsimony) sizeTournament(population []EvaluatedCortex, first, second int) int {
+
+	probability := DEFAULT_SIZE_TOURNAMENT_PROBABILITY
+	if p.SizeTournamentProbability != nil {
+		probability = *p.SizeTournamentProbability
+	}
+
+	smaller, larger := first, second
+	if Complexity(population[second].Cortex) < Complexity(population[first].Cortex) {
+		smaller, larger = second, first
+	}
+	if rand.Float64() < probability {
+		return smaller
+	}
+	return larger
+
+}
+
+func truncateSelection(population []EvaluatedCortex, numSurvivors int) []EvaluatedCortex {
+	survivors := make([]EvaluatedCortex, 0)
+	for i, evaldCortex := range population {
+		survivors = append(survivors, evaldCortex)
+		if i >= (numSurvivors - 1) {
+			break
+		}
+	}
+	return survivors
+}
+
+// Sorts by fitness, rounded down to a multiple of the tolerance, and
+// then by complexity
+type lexicographicSorter struct {
+	population []EvaluatedCortex
+	tolerance  float64
+}
+
+func (s lexicographicSorter) Len() int {
+	return len(s.population)
+}
+
+func (s lexicographicSorter) Less(i, j int) bool {
+	fitnessI := s.roundedFitness(s.population[i])
+	fitnessJ := s.roundedFitness(s.population[j])
+	if fitnessI != fitnessJ {
+		return fitnessI > fitnessJ
+	}
+	return Complexity(s.population[i].Cortex) < Complexity(s.population[j].Cortex)
+}
+
+func (s lexicographicSorter) Swap(i, j int) {
+	s.population[i], s.population[j] = s.population[j], s.population[i]
+}
+
+func (s lexicographicSorter) roundedFitness(evaldCortex EvaluatedCortex) float64 {
+	if s.tolerance <= 0 {
+		return evaldCortex.Fitness
+	}
+	return math.Floor(evaldCortex.Fitness / s.tolerance)
+}
+
+// A Scape which subtracts a complexity penalty from the fitness
+// computed by the wrapped scape
+type ParsimonyScape struct {
+	Scape   Scape
+	Penalty ComplexityPenalty
+}
+
+func (scape ParsimonyScape) Fitness(cortex *ng.Cortex) float64 {
+	return scape.Scape.Fitness(cortex) - scape.Penalty.Penalty(cortex)
+}
+
+func (scape ParsimonyScape) FitnessAgainst(cortex *ng.Cortex, opponent *ng.Cortex) float64 {
+	return scape.Scape.FitnessAgainst(cortex, opponent) - scape.Penalty.Penalty(cortex)
+}
+
+func (scape ParsimonyScape) StartGeneration(generation int, bestFitness float64) {
+	startGeneration([]Scape{scape.Scape}, generation, bestFitness)
+}
+
+func (scape ParsimonyScape) Sampled() bool {
+	return isSampled(scape.Scape)
+}
+
+func (scape ParsimonyScape) FullFitness(cortex *ng.Cortex) float64 {
+	return fullFitness(scape.Scape, cortex) - scape.Penalty.Penalty(cortex)
+}
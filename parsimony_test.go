@@ -0,0 +1,140 @@
+package neurvolve
+
+import (
+	"github.com/couchbaselabs/go.assert"
+	ng "github.com/maxxk/neurgo"
+	"testing"
+)
+
+func TestComplexityPenalty(t *testing.T) {
+
+	cortex := SingleNeuronCortex("cortex")
+	assert.Equals(t, NumConnections(cortex), 1)
+	assert.Equals(t, TotalWeightMagnitude(cortex), 1.0)
+	assert.Equals(t, Complexity(cortex), 2)
+
+	penalty := ComplexityPenalty{
+		NeuronPenalty:     1,
+		ConnectionPenalty: 10,
+		WeightPenalty:     100,
+	}
+	assert.Equals(t, penalty.Penalty(cortex), 111.0)
+
+	scape := ParsimonyScape{
+		Scape:   ConstantScape{fitness: 1000},
+		Penalty: penalty,
+	}
+	assert.Equals(t, scape.Fitness(cortex), 889.0)
+
+}
+
+func TestLexicographicParsimony(t *testing.T) {
+
+	large := EvaluatedCortex{Cortex: BasicCortex(), Fitness: 1.05}
+	small := EvaluatedCortex{Cortex: SingleNeuronCortex("small"), Fitness: 1.0}
+	population := []EvaluatedCortex{large, small}
+
+	// without tolerance, the fitter cortex wins
+	parsimony := &Parsimony{Scheme: PARSIMONY_LEXICOGRAPHIC}
+	survivors := parsimony.selectSurvivors(population, 1)
+	assert.Equals(t, survivors[0], large)
+
+	// within tolerance, the smaller cortex wins
+	parsimony.Tolerance = 0.5
+	survivors = parsimony.selectSurvivors(population, 1)
+	assert.Equals(t, survivors[0], small)
+
+	// in different buckets, the fitter cortex wins even though the
+	// difference is below the tolerance
+	large.Fitness, small.Fitness = 1.01, 0.99
+	parsimony.Tolerance = 0.1
+	survivors = parsimony.selectSurvivors([]EvaluatedCortex{large, small}, 1)
+	assert.Equals(t, survivors[0], large)
+
+}
+
+func TestDoubleTournamentParsimony(t *testing.T) {
+
+	ng.SeedRandom()
+
+	population := []EvaluatedCortex{
+		{Cortex: BasicCortex(), Fitness: 4.0},
+		{Cortex: SingleNeuronCortex("c1"), Fitness: 3.0},
+		{Cortex: SingleNeuronCortex("c2"), Fitness: 2.0},
+		{Cortex: SingleNeuronCortex("c3"), Fitness: 1.0},
+	}
+
+	parsimony := &Parsimony{Scheme: PARSIMONY_DOUBLE_TOURNAMENT}
+	survivors := parsimony.selectSurvivors(population, 2)
+	assert.Equals(t, len(survivors), 2)
+	assert.True(t, survivors[0].Cortex != survivors[1].Cortex)
+	assert.True(t, survivors[0].Fitness >= survivors[1].Fitness)
+
+}
+
+func TestParsimonyPenaltyOnlyAffectsSelection(t *testing.T) {
+
+	large := EvaluatedCortex{Cortex: BasicCortex(), Fitness: 1.5}
+	small := EvaluatedCortex{Cortex: SingleNeuronCortex("small"), Fitness: 1.0}
+
+	// the penalty makes the smaller cortex survive, with its own fitness
+	parsimony := &Parsimony{Penalty: ComplexityPenalty{NeuronPenalty: 1}}
+	survivors := parsimony.selectSurvivors([]EvaluatedCortex{large, small}, 1)
+	assert.Equals(t, survivors[0], small)
+
+	// a cortex which reaches the threshold isn't held back by the penalty
+	pt := &PopulationTrainer{
+		FitnessThreshold: 1,
+		MaxGenerations:   1,
+		CortexMutator:    NoOpMutator,
+		Parsimony:        &Parsimony{Penalty: ComplexityPenalty{NeuronPenalty: 100}},
+	}
+	population := []*ng.Cortex{SingleNeuronCortex("cortex1"), SingleNeuronCortex("cortex2")}
+	trained, succeeded := pt.Train(population, ConstantScape{1}, NewNullRecorder())
+	assert.True(t, succeeded)
+	assert.Equals(t, trained[0].Fitness, 1.0)
+
+}
+
+func TestSizeTournamentProbabilityZero(t *testing.T) {
+
+	population := []EvaluatedCortex{
+		{Cortex: SingleNeuronCortex("small"), Fitness: 1.0},
+		{Cortex: BasicCortex(), Fitness: 1.0},
+	}
+	never := 0.0
+	parsimony := &Parsimony{SizeTournamentProbability: &never}
+	for i := 0; i < 10; i++ {
+		assert.Equals(t, parsimony.sizeTournament(population, 0, 1), 1)
+	}
+
+}
+
+func TestParsimonyScapeStartGeneration(t *testing.T) {
+
+	recording := &GenerationRecordingScape{ConstantScape: ConstantScape{1}}
+	scape := ParsimonyScape{Scape: recording, Penalty: ComplexityPenalty{NeuronPenalty: 0.1}}
+
+	pt := &PopulationTrainer{
+		FitnessThreshold: 1000,
+		MaxGenerations:   2,
+		CortexMutator:    NoOpMutator,
+	}
+	population := []*ng.Cortex{SingleNeuronCortex("cortex1"), SingleNeuronCortex("cortex2")}
+	pt.Train(population, scape, NewNullRecorder())
+
+	assert.Equals(t, len(recording.generations), 2)
+
+}
+
+type ConstantScape struct {
+	fitness float64
+}
+
+func (scape ConstantScape) Fitness(cortex *ng.Cortex) float64 {
+	return scape.fitness
+}
+
+func (scape ConstantScape) FitnessAgainst(cortex *ng.Cortex, opponent *ng.Cortex) float64 {
+	return scape.fitness
+}
@@ -0,0 +1,124 @@
+package neurvolve
+
+import (
+	"github.com/couchbaselabs/logg"
+	ng "github.com/maxxk/neurgo"
+	"math"
+)
+
+// Parameters of the cart and pole, as in Barto, Sutton and Anderson (1983)
+const (
+	POLE_GRAVITY       = 9.8
+	POLE_CART_MASS     = 1.0
+	POLE_MASS          = 0.1
+	POLE_HALF_LENGTH   = 0.5
+	POLE_FORCE         = 10.0
+	POLE_TIME_STEP     = 0.02
+	POLE_TRACK_LIMIT   = 2.4
+	POLE_ANGLE_LIMIT   = 12 * math.Pi / 180
+	DEFAULT_POLE_STEPS = 1000
+)
+
+// Balancing a pole on a cart, for cortexes with a sensor of length 4
+// (cart position and velocity, pole angle and angular velocity, scaled
+// to roughly [-1, 1]) and an actuator of length 2 (push left, push right),
+// where the larger output decides the direction of a fixed force.
+// The fitness is the fraction of MaxSteps before the pole fell or the
+// cart left the track.
+type PoleBalancingScape struct {
+
+	// Defaults to DEFAULT_POLE_STEPS
+	MaxSteps int
+
+	// Angle of the pole at the start, in radians
+	InitialAngle float64
+}
+
+type cartPoleState struct {
+	x, xVelocity, angle, angularVelocity float64
+}
+
+func NewPoleBalancingScape() *PoleBalancingScape {
+	return &PoleBalancingScape{InitialAngle: 0.05}
+}
+
+// A cortex with the sensor and actuator of a PoleBalancingScape, where
+// each output neuron is connected to the sensor
+func PoleBalancingCortex() *ng.Cortex {
+	factory := &PopulationFactory{
+		Sensors:   []SensorSpec{{Name: "cart-pole", VectorLength: 4}},
+		Actuators: []ActuatorSpec{{Name: "push", VectorLength: 2}},
+		Template:  TEMPLATE_MINIMAL,
+	}
+	return factory.NewCortex()
+}
+
+func (scape PoleBalancingScape) Fitness(cortex *ng.Cortex) float64 {
+	maxSteps := scape.maxSteps()
+	return float64(scape.stepsBalanced(cortex, maxSteps)) / float64(maxSteps)
+}
+
+func (scape PoleBalancingScape) FitnessAgainst(cortex *ng.Cortex, opponentCortex *ng.Cortex) (fitness float64) {
+	logg.LogPanic("Cannot calculate fitness against another cortex")
+	return 0.0
+}
+
+func (scape PoleBalancingScape) stepsBalanced(cortex *ng.Cortex, maxSteps int) int {
+
+	stepper := StartCortex(cortex)
+	defer stepper.Stop()
+
+	state := cartPoleState{angle: scape.InitialAngle}
+	for step := 0; step < maxSteps; step++ {
+		outputs := stepper.Step(state.sensorInputs())
+		force := POLE_FORCE
+		if outputs[0][0] > outputs[0][1] {
+			force = -POLE_FORCE
+		}
+		state = state.next(force)
+		if state.failed() {
+			return step
+		}
+	}
+	return maxSteps
+
+}
+
+func (scape PoleBalancingScape) maxSteps() int {
+	if scape.MaxSteps <= 0 {
+		return DEFAULT_POLE_STEPS
+	}
+	return scape.MaxSteps
+}
+
+func (state cartPoleState) sensorInputs() [][]float64 {
+	return [][]float64{{
+		state.x / POLE_TRACK_LIMIT,
+		state.xVelocity / 2,
+		state.angle / POLE_ANGLE_LIMIT,
+		state.angularVelocity / 2,
+	}}
+}
+
+// The state after applying the force for one time step
+func (state cartPoleState) next(force float64) cartPoleState {
+
+	totalMass := POLE_CART_MASS + POLE_MASS
+	cos, sin := math.Cos(state.angle), math.Sin(state.angle)
+
+	temp := (force + POLE_MASS*POLE_HALF_LENGTH*state.angularVelocity*state.angularVelocity*sin) / totalMass
+	angularAcceleration := (POLE_GRAVITY*sin - cos*temp) / (POLE_HALF_LENGTH * (4.0/3.0 - POLE_MASS*cos*cos/totalMass))
+	acceleration := temp - POLE_MASS*POLE_HALF_LENGTH*angularAcceleration*cos/totalMass
+
+	return cartPoleState{
+		x:               state.x + POLE_TIME_STEP*state.xVelocity,
+		xVelocity:       state.xVelocity + POLE_TIME_STEP*acceleration,
+		angle:           state.angle + POLE_TIME_STEP*state.angularVelocity,
+		angularVelocity: state.angularVelocity + POLE_TIME_STEP*angularAcceleration,
+	}
+
+}
+
+func (state cartPoleState) failed() bool {
+	return math.Abs(state.x) > POLE_TRACK_LIMIT || math.Abs(state.angle) > POLE_ANGLE_LIMIT
+}
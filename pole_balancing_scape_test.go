@@ -0,0 +1,41 @@
+package neurvolve
+
+import (
+	"github.com/couchbaselabs/go.assert"
+	ng "github.com/maxxk/neurgo"
+	"testing"
+)
+
+// A pole balancing cortex whose push right output is the weighted sum of
+// its inputs, and whose push left output is its negation
+func LinearControllerCortex(weights []float64) *ng.Cortex {
+	cortex := PoleBalancingCortex()
+	for i, neuron := range cortex.Neurons {
+		neuron.ActivationFunction = ng.EncodableIdentity()
+		neuron.Bias = 0
+		sign := -1.0
+		if i == 1 {
+			sign = 1.0
+		}
+		for j := range neuron.Inbound[0].Weights {
+			neuron.Inbound[0].Weights[j] = sign * weights[j]
+		}
+	}
+	return cortex
+}
+
+func TestPoleBalancingScape(t *testing.T) {
+
+	scape := NewPoleBalancingScape()
+
+	// always pushing right soon drops the pole
+	alwaysRight := LinearControllerCortex([]float64{0, 0, 0, 0})
+	alwaysRight.Neurons[1].Bias = 1
+	fitness := scape.Fitness(alwaysRight)
+	assert.True(t, fitness > 0 && fitness < 0.1)
+
+	// pushing towards where the pole leans, while keeping the cart centered
+	controller := LinearControllerCortex([]float64{0.1, 0, 1, 0.3})
+	assert.Equals(t, scape.Fitness(controller), 1.0)
+
+}
@@ -0,0 +1,284 @@
+package neurvolve
+
+import (
+	"fmt"
+	"github.com/couchbaselabs/logg"
+	ng "github.com/maxxk/neurgo"
+	"math/rand"
+)
+
+type TopologyTemplate string
+
+const (
+	// Every output neuron is connected directly to every sensor
+	TEMPLATE_MINIMAL TopologyTemplate = "minimal"
+
+	// A single fully connected hidden layer of HiddenLayerWidth neurons
+	TEMPLATE_HIDDEN_LAYER TopologyTemplate = "hidden_layer"
+
+	// A single hidden layer of HiddenLayerWidth neurons, where each
+	// possible connection exists with ConnectionProbability
+	TEMPLATE_RANDOM_SPARSE TopologyTemplate = "random_sparse"
+)
+
+const DEFAULT_CONNECTION_PROBABILITY = 0.5
+
+// Layer indexes of the nodes in generated cortexes
+const (
+	SENSOR_LAYER   = 0.0
+	HIDDEN_LAYER   = 0.25
+	OUTPUT_LAYER   = 0.5
+	ACTUATOR_LAYER = 1.0
+)
+
+type SensorSpec struct {
+	Name         string
+	VectorLength int
+}
+
+// An actuator with a VectorLength of n is fed by n output neurons
+type ActuatorSpec struct {
+	Name         string
+	VectorLength int
+}
+
+// Generates cortexes with the given sensors and actuators from a
+// topology template, with random weights, biases and activation functions.
+// Every cortex and node gets a new uuid, prefixed by its name.
+type PopulationFactory struct {
+	Sensors   []SensorSpec
+	Actuators []ActuatorSpec
+	Template  TopologyTemplate
+
+	// Number of hidden neurons of the hidden layer templates
+	HiddenLayerWidth int
+
+	// Probability of each connection in the random sparse template.
+	// Defaults to DEFAULT_CONNECTION_PROBABILITY.
+	ConnectionProbability float64
+
+	// Activation functions to choose from at random.
+	// Defaults to ng.AllEncodableActivations().
+	Activations []*ng.EncodableActivation
+}
+
+// Generate a population of size cortexes
+func (factory *PopulationFactory) NewPopulation(size int) []*ng.Cortex {
+	population := make([]*ng.Cortex, 0, size)
+	for i := 0; i < size; i++ {
+		population = append(population, factory.NewCortex())
+	}
+	return population
+}
+
+// Generate a single cortex
+func (factory *PopulationFactory) NewCortex() *ng.Cortex {
+
+	factory.validate()
+
+	sensors := factory.newSensors()
+	actuators := factory.newActuators()
+
+	outputNeurons := make([]*ng.Neuron, 0)
+	for _, actuator := range actuators {
+		for i := 0; i < actuator.VectorLength; i++ {
+			neuron := factory.newNeuron("output-neuron", OUTPUT_LAYER)
+			neuron.ConnectOutbound(actuator)
+			actuator.ConnectInbound(neuron)
+			outputNeurons = append(outputNeurons, neuron)
+		}
+	}
+
+	neurons := make([]*ng.Neuron, 0)
+	switch factory.Template {
+	case TEMPLATE_MINIMAL:
+		for _, neuron := range outputNeurons {
+			for _, sensor := range sensors {
+				connectSensor(sensor, neuron)
+			}
+		}
+	case TEMPLATE_HIDDEN_LAYER, TEMPLATE_RANDOM_SPARSE:
+		hiddenNeurons := make([]*ng.Neuron, 0)
+		for i := 0; i < factory.HiddenLayerWidth; i++ {
+			hiddenNeurons = append(hiddenNeurons, factory.newNeuron("hidden-neuron", HIDDEN_LAYER))
+		}
+		if factory.Template == TEMPLATE_HIDDEN_LAYER {
+			connectFully(sensors, hiddenNeurons, outputNeurons)
+		} else {
+			factory.connectSparsely(sensors, hiddenNeurons, outputNeurons)
+		}
+		neurons = append(neurons, hiddenNeurons...)
+	}
+	neurons = append(neurons, outputNeurons...)
+
+	cortex := &ng.Cortex{
+		NodeId: ng.NewCortexId(fmt.Sprintf("cortex-%s", ng.NewUuid())),
+	}
+	cortex.SetSensors(sensors)
+	cortex.SetNeurons(neurons)
+	cortex.SetActuators(actuators)
+
+	return cortex
+
+}
+
+func (factory *PopulationFactory) validate() {
+	if len(factory.Sensors) == 0 || len(factory.Actuators) == 0 {
+		logg.LogPanic("Need at least one sensor and one actuator")
+	}
+	for _, spec := range factory.Sensors {
+		if spec.VectorLength <= 0 {
+			logg.LogPanic("Sensor %q needs a positive VectorLength", spec.Name)
+		}
+	}
+	for _, spec := range factory.Actuators {
+		if spec.VectorLength <= 0 {
+			logg.LogPanic("Actuator %q needs a positive VectorLength", spec.Name)
+		}
+	}
+	switch factory.Template {
+	case TEMPLATE_MINIMAL:
+	case TEMPLATE_HIDDEN_LAYER, TEMPLATE_RANDOM_SPARSE:
+		if factory.HiddenLayerWidth <= 0 {
+			logg.LogPanic("Template %v needs a positive HiddenLayerWidth", factory.Template)
+		}
+	default:
+		logg.LogPanic("Unknown topology template: %q", factory.Template)
+	}
+}
+
+func (factory *PopulationFactory) newSensors() []*ng.Sensor {
+	sensors := make([]*ng.Sensor, 0)
+	for _, spec := range factory.Sensors {
+		sensor := &ng.Sensor{
+			NodeId:       ng.NewSensorId(uniqueName(spec.Name, "sensor"), SENSOR_LAYER),
+			VectorLength: spec.VectorLength,
+		}
+		sensor.Init()
+		sensors = append(sensors, sensor)
+	}
+	return sensors
+}
+
+func (factory *PopulationFactory) newActuators() []*ng.Actuator {
+	actuators := make([]*ng.Actuator, 0)
+	for _, spec := range factory.Actuators {
+		actuator := &ng.Actuator{
+			NodeId:       ng.NewActuatorId(uniqueName(spec.Name, "actuator"), ACTUATOR_LAYER),
+			VectorLength: spec.VectorLength,
+		}
+		actuator.Init()
+		actuators = append(actuators, actuator)
+	}
+	return actuators
+}
+
+func (factory *PopulationFactory) newNeuron(name string, layerIndex float64) *ng.Neuron {
+	neuron := &ng.Neuron{
+		ActivationFunction: factory.randomActivation(),
+		NodeId:             ng.NewNeuronId(uniqueName(name, "neuron"), layerIndex),
+		Bias:               RandomBias(),
+	}
+	neuron.Init()
+	return neuron
+}
+
+func (factory *PopulationFactory) randomActivation() *ng.EncodableActivation {
+	activations := factory.Activations
+	if len(activations) == 0 {
+		activations = ng.AllEncodableActivations()
+	}
+	return activations[RandomIntInRange(0, len(activations))]
+}
+
+func (factory *PopulationFactory) connectionProbability() float64 {
+	if factory.ConnectionProbability == 0 {
+		return DEFAULT_CONNECTION_PROBABILITY
+	}
+	return factory.ConnectionProbability
+}
+
+// Connect each possible pair with the connection probability, while
+// making sure that every hidden neuron has at least one inbound and one
+// outbound connection, every output neuron at least one inbound one, and
+// every sensor at least one outbound one, so that no input is ignored.
+func (factory *PopulationFactory) connectSparsely(sensors []*ng.Sensor, hiddenNeurons, outputNeurons []*ng.Neuron) {
+
+	probability := factory.connectionProbability()
+
+	sensorHasOutbound := make(map[*ng.Sensor]bool)
+	for _, hiddenNeuron := range hiddenNeurons {
+		connected := false
+		for _, sensor := range sensors {
+			if rand.Float64() < probability {
+				connectSensor(sensor, hiddenNeuron)
+				sensorHasOutbound[sensor] = true
+				connected = true
+			}
+		}
+		if !connected {
+			sensor := sensors[RandomIntInRange(0, len(sensors))]
+			connectSensor(sensor, hiddenNeuron)
+			sensorHasOutbound[sensor] = true
+		}
+	}
+
+	for _, sensor := range sensors {
+		if !sensorHasOutbound[sensor] {
+			connectSensor(sensor, hiddenNeurons[RandomIntInRange(0, len(hiddenNeurons))])
+		}
+	}
+
+	hasOutbound := make(map[*ng.Neuron]bool)
+	for _, outputNeuron := range outputNeurons {
+		connected := false
+		for _, hiddenNeuron := range hiddenNeurons {
+			if rand.Float64() < probability {
+				connectNeurons(hiddenNeuron, outputNeuron)
+				hasOutbound[hiddenNeuron] = true
+				connected = true
+			}
+		}
+		if !connected {
+			hiddenNeuron := hiddenNeurons[RandomIntInRange(0, len(hiddenNeurons))]
+			connectNeurons(hiddenNeuron, outputNeuron)
+			hasOutbound[hiddenNeuron] = true
+		}
+	}
+
+	for _, hiddenNeuron := range hiddenNeurons {
+		if !hasOutbound[hiddenNeuron] {
+			outputNeuron := outputNeurons[RandomIntInRange(0, len(outputNeurons))]
+			connectNeurons(hiddenNeuron, outputNeuron)
+		}
+	}
+
+}
+
+func connectFully(sensors []*ng.Sensor, hiddenNeurons, outputNeurons []*ng.Neuron) {
+	for _, hiddenNeuron := range hiddenNeurons {
+		for _, sensor := range sensors {
+			connectSensor(sensor, hiddenNeuron)
+		}
+		for _, outputNeuron := range outputNeurons {
+			connectNeurons(hiddenNeuron, outputNeuron)
+		}
+	}
+}
+
+func connectSensor(sensor *ng.Sensor, neuron *ng.Neuron) {
+	sensor.ConnectOutbound(neuron)
+	neuron.ConnectInboundWeighted(sensor, randomWeights(sensor.VectorLength))
+}
+
+func connectNeurons(source, target *ng.Neuron) {
+	source.ConnectOutbound(target)
+	target.ConnectInboundWeighted(source, randomWeights(1))
+}
+
+func uniqueName(name, defaultName string) string {
+	if name == "" {
+		name = defaultName
+	}
+	return fmt.Sprintf("%s-%s", name, ng.NewUuid())
+}
@@ -0,0 +1,107 @@
+package neurvolve
+
+import (
+	"github.com/couchbaselabs/go.assert"
+	ng "github.com/maxxk/neurgo"
+	"testing"
+)
+
+func TestPopulationFactoryTemplates(t *testing.T) {
+
+	factory := &PopulationFactory{
+		Sensors:          []SensorSpec{{Name: "position", VectorLength: 2}, {VectorLength: 1}},
+		Actuators:        []ActuatorSpec{{Name: "motor", VectorLength: 2}},
+		HiddenLayerWidth: 3,
+	}
+
+	expectedNumNeurons := map[TopologyTemplate]int{
+		TEMPLATE_MINIMAL:       2,
+		TEMPLATE_HIDDEN_LAYER:  5,
+		TEMPLATE_RANDOM_SPARSE: 5,
+	}
+
+	for template, numNeurons := range expectedNumNeurons {
+
+		factory.Template = template
+		population := factory.NewPopulation(4)
+		assert.Equals(t, len(population), 4)
+
+		uuids := make(map[string]bool)
+		for _, cortex := range population {
+
+			assert.Equals(t, len(cortex.Sensors), 2)
+			assert.Equals(t, len(cortex.Neurons), numNeurons)
+			assert.Equals(t, len(cortex.Actuators), 1)
+			assert.Equals(t, len(cortex.Actuators[0].Inbound), 2)
+
+			nodeIds := []*ng.NodeId{cortex.NodeId}
+			for _, sensor := range cortex.Sensors {
+				nodeIds = append(nodeIds, sensor.NodeId)
+			}
+			for _, neuron := range cortex.Neurons {
+				assert.True(t, len(neuron.Inbound) > 0)
+				nodeIds = append(nodeIds, neuron.NodeId)
+			}
+			for _, actuator := range cortex.Actuators {
+				nodeIds = append(nodeIds, actuator.NodeId)
+			}
+			for _, nodeId := range nodeIds {
+				assert.False(t, uuids[nodeId.UUID])
+				uuids[nodeId.UUID] = true
+			}
+
+			// the generated cortexes are feed-forward and consistent
+			compiled, err := CompileCortex(cortex)
+			assert.True(t, err == nil)
+			outputs := compiled.Compute([][]float64{{0.5, -0.5}, {1}})
+			assert.Equals(t, len(outputs[0]), 2)
+
+		}
+
+	}
+
+}
+
+func TestPopulationFactoryRandomSparse(t *testing.T) {
+
+	factory := &PopulationFactory{
+		Sensors:               []SensorSpec{{VectorLength: 1}, {VectorLength: 1}},
+		Actuators:             []ActuatorSpec{{VectorLength: 1}},
+		Template:              TEMPLATE_RANDOM_SPARSE,
+		HiddenLayerWidth:      4,
+		ConnectionProbability: 0.01,
+		Activations:           []*ng.EncodableActivation{ng.EncodableSigmoid()},
+	}
+
+	for _, cortex := range factory.NewPopulation(10) {
+
+		// every hidden neuron feeds the output neuron
+		outputNeuron := cortex.Neurons[len(cortex.Neurons)-1]
+		assert.Equals(t, len(outputNeuron.Inbound), 4)
+
+		for _, neuron := range cortex.Neurons {
+			assert.True(t, len(neuron.Inbound) > 0)
+			assert.Equals(t, neuron.ActivationFunction.Name, ng.EncodableSigmoid().Name)
+		}
+
+	}
+
+}
+
+func TestPopulationFactoryRandomSparseConnectsEverySensor(t *testing.T) {
+
+	factory := &PopulationFactory{
+		Sensors:               []SensorSpec{{VectorLength: 1}, {VectorLength: 2}, {VectorLength: 1}, {VectorLength: 3}},
+		Actuators:             []ActuatorSpec{{VectorLength: 1}},
+		Template:              TEMPLATE_RANDOM_SPARSE,
+		HiddenLayerWidth:      2,
+		ConnectionProbability: 1e-9,
+	}
+
+	for _, cortex := range factory.NewPopulation(10) {
+		for _, sensor := range cortex.Sensors {
+			assert.True(t, len(sensor.Outbound) > 0)
+		}
+	}
+
+}
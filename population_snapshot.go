@@ -0,0 +1,58 @@
+package neurvolve
+
+import (
+	"sync/atomic"
+)
+
+// An immutable copy of a fully evaluated population
+type PopulationSnapshot struct {
+	Generation int
+	Population EvaluatedCortexes
+}
+
+// Holds the snapshot of the most recently completed generation.  The
+// trainer publishes a fresh copy after every generation, and readers get
+// the latest one immediately, without waiting for the trainer.
+// A nil *SnapshotStore is valid: it ignores publishes and has no snapshot.
+type SnapshotStore struct {
+	latest atomic.Value // *PopulationSnapshot
+}
+
+func NewSnapshotStore() *SnapshotStore {
+	return &SnapshotStore{}
+}
+
+// Store a deep copy of the population as the latest snapshot
+func (store *SnapshotStore) Publish(generation int, population []EvaluatedCortex) {
+
+	if store == nil {
+		return
+	}
+
+	populationCopy := make(EvaluatedCortexes, 0, len(population))
+	for _, evaldCortex := range population {
+		evaldCortexCopy := evaldCortex
+		evaldCortexCopy.Cortex = evaldCortex.Cortex.Copy()
+		if evaldCortex.Strategy != nil {
+			evaldCortexCopy.Strategy = evaldCortex.Strategy.Copy()
+		}
+		populationCopy = append(populationCopy, evaldCortexCopy)
+	}
+
+	snapshot := &PopulationSnapshot{
+		Generation: generation,
+		Population: populationCopy,
+	}
+	store.latest.Store(snapshot)
+
+}
+
+// The latest snapshot, or nil if nothing has been published yet.
+// Callers must not modify it.
+func (store *SnapshotStore) Latest() *PopulationSnapshot {
+	if store == nil {
+		return nil
+	}
+	snapshot, _ := store.latest.Load().(*PopulationSnapshot)
+	return snapshot
+}
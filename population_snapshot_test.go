@@ -0,0 +1,52 @@
+package neurvolve
+
+import (
+	"github.com/couchbaselabs/go.assert"
+	"testing"
+)
+
+func TestSnapshotStore(t *testing.T) {
+
+	store := NewSnapshotStore()
+	assert.True(t, store.Latest() == nil)
+
+	cortex := SingleNeuronCortex("cortex")
+	population := []EvaluatedCortex{
+		{
+			Cortex:              cortex,
+			Fitness:             1.5,
+			ParentId:            "parent",
+			CreatedInGeneration: 2,
+			Strategy:            NewMutationStrategy(1.0, false),
+		},
+	}
+	store.Publish(3, population)
+
+	// changes to the trainer's population don't leak into the snapshot
+	population[0].Fitness = 100
+	population[0].Strategy.StepSize = 100
+
+	snapshot := store.Latest()
+	assert.Equals(t, snapshot.Generation, 3)
+	assert.Equals(t, len(snapshot.Population), 1)
+
+	evaldCortex := snapshot.Population[0]
+	assert.Equals(t, evaldCortex.Fitness, 1.5)
+	assert.Equals(t, evaldCortex.ParentId, "parent")
+	assert.Equals(t, evaldCortex.CreatedInGeneration, 2)
+	assert.Equals(t, evaldCortex.Strategy.StepSize, 1.0)
+	assert.True(t, evaldCortex.Cortex != cortex)
+	assert.Equals(t, evaldCortex.Cortex.NodeId.UUID, "cortex")
+
+}
+
+func TestNilSnapshotStore(t *testing.T) {
+
+	var store *SnapshotStore
+	store.Publish(0, []EvaluatedCortex{})
+	assert.True(t, store.Latest() == nil)
+
+	pt := &PopulationTrainer{}
+	assert.True(t, pt.GetPopulationSnapshot() == nil)
+
+}
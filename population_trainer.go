@@ -4,34 +4,145 @@ import (
 	"fmt"
 	"github.com/couchbaselabs/logg"
 	ng "github.com/maxxk/neurgo"
+	"math"
 	"sort"
 )
 
 func init() {
 }
 
+// Called with the fittest cortex of each generation and its fitness
+// against the PopulationTrainer's ValidationScape
+type ValidationHook func(generation int, fittest EvaluatedCortex, validationFitness float64)
+
 type PopulationTrainer struct {
-	CortexMutator       CortexMutator
-	FitnessThreshold    float64
-	MaxGenerations      int
-	CurrentGeneration   int
-	NumOpponents        int
-	SnapshotRequestChan chan chan EvaluatedCortexes
+	CortexMutator     CortexMutator
+	FitnessThreshold  float64
+	MaxGenerations    int
+	CurrentGeneration int
+	NumOpponents      int
+
+	// If set, a copy of each evaluated generation is published to it
+	Snapshots *SnapshotStore
+
+	// How many times each cortex is evaluated per generation.  Scapes
+	// with random starting conditions should use more than one, so that
+	// a single lucky evaluation doesn't decide culling.  Defaults to 1.
+	NumEvaluations int
+
+	// Combines the fitness scores of a cortex into its Fitness.
+	// Defaults to AggregateMean.
+	FitnessAggregator FitnessAggregator
+
+	// If true, survivors keep the scores from previous generations and
+	// new scores are added to them, rather than being replaced.
+	AccumulateFitness bool
+
+	// Optional pressure towards smaller cortexes
+	Parsimony *Parsimony
+
+	// If set, this is used instead of CortexMutator.  Each offspring
+	// inherits its parent's MutationStrategy, which is adapted before
+	// being used to mutate the offspring.
+	AdaptiveCortexMutator AdaptiveCortexMutator
+
+	// Strategy given to the initial population when using an
+	// AdaptiveCortexMutator.  Defaults to a single step size of
+	// DEFAULT_STD_DEVIATION.
+	InitialStrategy *MutationStrategy
+
+	// If set, the stats of each generation are added to it
+	Metrics *MetricsHistory
+
+	// If set, training can be paused, resumed, stopped and adjusted
+	// at generation boundaries
+	Control *TrainingControl
+
+	// If set, an event is published for every generation and match
+	Events *EventBroadcaster
+
+	// If set, the fittest cortex of every generation is saved to it
+	Artifacts ArtifactStore
+
+	// If set, the fittest cortex of every generation is also evaluated
+	// against it, typically a TrainingSampleScape with held out samples.
+	// The result is added to the generation stats and passed to the
+	// ValidationHook, but does not affect selection.
+	ValidationScape Scape
+	ValidationHook  ValidationHook
+
+	// Size of the population made by TrainFrom.  Defaults to
+	// DEFAULT_POPULATION_SIZE.
+	PopulationSize int
+
+	// Recorder used by TrainFrom.  Defaults to a null recorder.
+	Recorder Recorder
+
+	// If set, the evaluations of the scape are counted against it and
+	// training stops once it is exhausted.  If it has a limit,
+	// MaxGenerations may be 0, for no limit on the number of generations.
+	Budget *EvaluationBudget
+
+	// Tracks the run for TrainFrom
+	progress *trainProgress
+
+	// Raw fitness scores of each cortex.  Keyed by cortex rather than
+	// uuid, since the initial population may contain copies of the same cortex.
+	fitnessScores map[*ng.Cortex][]float64
 }
 
 func (pt *PopulationTrainer) Train(population []*ng.Cortex, scape Scape, recorder Recorder) (trainedPopulation []EvaluatedCortex, succeeded bool) {
 
+	pt.fitnessScores = make(map[*ng.Cortex][]float64)
+
 	evaldCortexes := pt.addEmptyFitnessScores(population)
 	recorder.AddGeneration(evaldCortexes)
 
-	for i := 0; i < pt.MaxGenerations; i++ {
+	pt.progress, scape = newTrainProgress(scape)
+	scape = pt.Budget.Scape(scape)
+
+	bestFitness := math.Inf(-1)
+	for i := 0; pt.withinMaxGenerations(i); i++ {
 
 		pt.CurrentGeneration = i
-		pt.publishSnapshot(evaldCortexes)
 
+		var stop bool
+		evaldCortexes, stop = pt.applyControl(evaldCortexes)
+		if stop {
+			logg.LogTo("NEURVOLVE", "Training stopped at generation %d", i)
+			pt.progress.stop(STOP_STOPPED)
+			trainedPopulation = evaldCortexes
+			return
+		}
+		if !pt.withinMaxGenerations(i) {
+			break
+		}
+		if pt.Budget.Exhausted() {
+			logg.LogTo("NEURVOLVE", "Evaluation budget exhausted at generation %d", i)
+			pt.progress.stop(STOP_BUDGET_EXHAUSTED)
+			trainedPopulation = evaldCortexes
+			return
+		}
+
+		if generationalScape, ok := scape.(GenerationalScape); ok {
+			generationalScape.StartGeneration(i, bestFitness)
+		}
 		evaldCortexes = pt.computeFitness(evaldCortexes, scape, recorder)
+		if len(evaldCortexes) > 0 {
+			bestFitness = evaldCortexes[0].Fitness
+			pt.progress.update(evaldCortexes[0].Cortex, bestFitness)
+		}
 
-		if pt.exceededFitnessThreshold(evaldCortexes) {
+		pt.Snapshots.Publish(i, evaldCortexes)
+		validationFitness := pt.computeValidationFitness(evaldCortexes)
+		pt.publishGenerationStats(evaldCortexes, validationFitness)
+		if len(evaldCortexes) > 0 {
+			saveArtifact(pt.Artifacts, evaldCortexes[0], i)
+		}
+
+		if pt.solve(evaldCortexes, scape) {
+			pt.Budget.recordSolution()
+			pt.progress.solve(evaldCortexes[0].Cortex, evaldCortexes[0].Fitness)
 			succeeded = true
 			trainedPopulation = evaldCortexes
 			return
@@ -46,30 +157,139 @@ func (pt *PopulationTrainer) Train(population []*ng.Cortex, scape Scape, recorde
 		trainedPopulation = evaldCortexes
 	}
 
+	pt.progress.stop(STOP_MAX_ITERATIONS)
 	return
 
 }
 
-func (pt *PopulationTrainer) publishSnapshot(evaldPopulation EvaluatedCortexes) {
-	select {
-	case responseChan := <-pt.SnapshotRequestChan:
-		evaldPopulationCopy := make(EvaluatedCortexes, 0)
-		for _, evaldCortex := range evaldPopulation {
-			evaldCortexCopy := EvaluatedCortex{
-				Fitness: evaldCortex.Fitness,
-				Cortex:  evaldCortex.Cortex.Copy(),
-			}
-			evaldPopulationCopy = append(evaldPopulationCopy, evaldCortexCopy)
+// Train a population of PopulationSize cortexes made by newCortex
+func (pt *PopulationTrainer) TrainFrom(newCortex CortexFactory, scape Scape) TrainResult {
+
+	population := make([]*ng.Cortex, pt.populationSize())
+	for i := range population {
+		cortex := newCortex()
+		cortex.NodeId = ng.NewCortexId(fmt.Sprintf("cortex-%s", ng.NewUuid()))
+		population[i] = cortex
+	}
+
+	recorder := pt.Recorder
+	if recorder == nil {
+		recorder = NewNullRecorder()
+	}
+
+	_, succeeded := pt.Train(population, scape, recorder)
+	return pt.progress.finish(succeeded)
+
+}
+
+// MaxGenerations only limits training without a limited budget, or if
+// it is set
+func (pt *PopulationTrainer) withinMaxGenerations(generation int) bool {
+	if pt.Budget.limited() && pt.MaxGenerations <= 0 {
+		return true
+	}
+	return generation < pt.MaxGenerations
+}
+
+func (pt *PopulationTrainer) GetPopulationSnapshot() *PopulationSnapshot {
+	return pt.Snapshots.Latest()
+}
+
+func (pt *PopulationTrainer) GetTrainingControl() *TrainingControl {
+	return pt.Control
+}
+
+// Wait while paused, then apply any changes requested through the Control.
+// Injected cortexes replace the last (ie, newest) members of the population.
+func (pt *PopulationTrainer) applyControl(population []EvaluatedCortex) (updatedPopulation []EvaluatedCortex, stop bool) {
+
+	updatedPopulation = population
+	if pt.Control == nil {
+		return
+	}
+
+	update := pt.Control.checkpoint(pt.CurrentGeneration)
+	if update.stop {
+		stop = true
+		return
+	}
+	if update.fitnessThreshold != nil {
+		logg.LogTo("NEURVOLVE", "FitnessThreshold: %v -> %v", pt.FitnessThreshold, *update.fitnessThreshold)
+		pt.FitnessThreshold = *update.fitnessThreshold
+	}
+	if update.maxGenerations != nil {
+		logg.LogTo("NEURVOLVE", "MaxGenerations: %v -> %v", pt.MaxGenerations, *update.maxGenerations)
+		pt.MaxGenerations = *update.maxGenerations
+	}
+
+	for i, cortex := range update.injected {
+		if i >= len(updatedPopulation) {
+			logg.LogTo("NEURVOLVE", "Population too small, dropping injected cortex: %v", cortex.NodeId.UUID)
+			continue
+		}
+		injected := EvaluatedCortex{
+			Cortex:              cortex,
+			ParentId:            cortex.NodeId.UUID, // no parent, set to self
+			CreatedInGeneration: pt.CurrentGeneration,
+		}
+		if pt.AdaptiveCortexMutator != nil {
+			injected.Strategy = pt.initialStrategy()
 		}
-		responseChan <- evaldPopulationCopy
-	default:
+		updatedPopulation[len(updatedPopulation)-1-i] = injected
+		logg.LogTo("NEURVOLVE", "Injected cortex: %v", cortex.NodeId.UUID)
+	}
+
+	return
+
+}
+
+func (pt *PopulationTrainer) GetEventBroadcaster() *EventBroadcaster {
+	return pt.Events
+}
+
+// Evaluate the fittest cortex of a freshly evaluated (and therefore
+// sorted) population against the ValidationScape, if there is one
+func (pt *PopulationTrainer) computeValidationFitness(evaldCortexes []EvaluatedCortex) *float64 {
+
+	if pt.ValidationScape == nil || len(evaldCortexes) == 0 {
+		return nil
+	}
+
+	fittest := evaldCortexes[0]
+	validationFitness := boundFitness(pt.ValidationScape.Fitness(fittest.Cortex))
+	logg.LogTo("NEURVOLVE", "Generation %d training fitness: %v validation fitness: %v", pt.CurrentGeneration, fittest.Fitness, validationFitness)
+	if pt.ValidationHook != nil {
+		pt.ValidationHook(pt.CurrentGeneration, fittest, validationFitness)
+	}
+	return &validationFitness
+
+}
+
+// Add the stats of a freshly evaluated (and therefore sorted)
+// population to the metrics history and publish them as an event
+func (pt *PopulationTrainer) publishGenerationStats(evaldCortexes []EvaluatedCortex, validationFitness *float64) {
+
+	if pt.Metrics == nil && pt.Events == nil {
+		return
+	}
+
+	stats := ComputeGenerationStats(pt.CurrentGeneration, evaldCortexes)
+	stats.ValidationFitness = validationFitness
+	if pt.Metrics != nil {
+		pt.Metrics.Add(stats)
 	}
+	if pt.Events != nil && len(evaldCortexes) > 0 {
+		pt.Events.PublishGeneration(stats, evaldCortexes[0])
+	}
+
+}
+
+func (pt *PopulationTrainer) GetMetricsHistory() *MetricsHistory {
+	return pt.Metrics
 }
 
-func (pt *PopulationTrainer) GetPopulationSnapshot() EvaluatedCortexes {
-	responseChan := make(chan EvaluatedCortexes)
-	pt.SnapshotRequestChan <- responseChan
-	return <-responseChan
+func (pt *PopulationTrainer) GetArtifactStore() ArtifactStore {
+	return pt.Artifacts
 }
 
 func (pt *PopulationTrainer) addEmptyFitnessScores(population []*ng.Cortex) (evaldPopulation []EvaluatedCortex) {
@@ -82,6 +302,9 @@ func (pt *PopulationTrainer) addEmptyFitnessScores(population []*ng.Cortex) (eva
 			ParentId: cortex.NodeId.UUID, // no parent, set to self
 			Fitness:  0.0,
 		}
+		if pt.AdaptiveCortexMutator != nil {
+			evaldCortex.Strategy = pt.initialStrategy()
+		}
 		evaldPopulation = append(evaldPopulation, evaldCortex)
 
 	}
@@ -95,27 +318,25 @@ func (pt *PopulationTrainer) computeFitness(population []EvaluatedCortex, scape
 	for i, evaldCortex := range population {
 		cortex := evaldCortex.Cortex
 
-		averageFitness := 0.0
-		if pt.NumOpponents > 0 {
-
-			opponents := pt.chooseRandomOpponents(cortex, population, pt.NumOpponents)
-
-			fitnessScores := make([]float64, len(opponents))
-			for j, opponent := range opponents {
-				score := scape.FitnessAgainst(cortex, opponent)
-				fitnessScores[j] = score
-				recorder.AddFitnessScore(score, cortex, opponent)
-			}
-			averageFitness = ng.Average(fitnessScores)
+		fitnessScores := make([]float64, 0)
+		if pt.AccumulateFitness {
+			fitnessScores = append(fitnessScores, pt.FitnessScores(cortex)...)
+		}
 
-		} else {
-			averageFitness = scape.Fitness(cortex)
+		for j := 0; j < pt.numEvaluations(); j++ {
+			score := pt.evaluate(cortex, population, scape, recorder)
+			fitnessScores = append(fitnessScores, score)
 		}
+		pt.setFitnessScores(cortex, fitnessScores)
+
+		fitness := pt.aggregateFitness(fitnessScores)
 
 		evaldCortexUpdated := EvaluatedCortex{
-			Cortex:   cortex,
-			ParentId: evaldCortex.ParentId,
-			Fitness:  averageFitness,
+			Cortex:              cortex,
+			ParentId:            evaldCortex.ParentId,
+			CreatedInGeneration: evaldCortex.CreatedInGeneration,
+			Fitness:             fitness,
+			Strategy:            evaldCortex.Strategy,
 		}
 		evaldCortexes[i] = evaldCortexUpdated
 
@@ -126,6 +347,63 @@ func (pt *PopulationTrainer) computeFitness(population []EvaluatedCortex, scape
 	return
 }
 
+// Evaluate the cortex once, either with the scape alone or, if
+// NumOpponents is set, as the average score against random opponents.
+// Scores are bounded, so that they can be published as json.
+func (pt *PopulationTrainer) evaluate(cortex *ng.Cortex, population []EvaluatedCortex, scape Scape, recorder Recorder) float64 {
+
+	if pt.NumOpponents <= 0 {
+		return boundFitness(scape.Fitness(cortex))
+	}
+
+	opponents := pt.chooseRandomOpponents(cortex, population, pt.NumOpponents)
+
+	fitnessScores := make([]float64, len(opponents))
+	for j, opponent := range opponents {
+		score := boundFitness(scape.FitnessAgainst(cortex, opponent))
+		fitnessScores[j] = score
+		recorder.AddFitnessScore(score, cortex, opponent)
+		if pt.Events != nil {
+			pt.Events.PublishMatch(pt.CurrentGeneration, score, cortex, opponent)
+		}
+	}
+	return ng.Average(fitnessScores)
+
+}
+
+// The raw scores which were aggregated into the cortex's current fitness
+func (pt *PopulationTrainer) FitnessScores(cortex *ng.Cortex) []float64 {
+	return pt.fitnessScores[cortex]
+}
+
+func (pt *PopulationTrainer) setFitnessScores(cortex *ng.Cortex, fitnessScores []float64) {
+	if pt.fitnessScores == nil {
+		pt.fitnessScores = make(map[*ng.Cortex][]float64)
+	}
+	pt.fitnessScores[cortex] = fitnessScores
+}
+
+func (pt *PopulationTrainer) populationSize() int {
+	if pt.PopulationSize <= 0 {
+		return DEFAULT_POPULATION_SIZE
+	}
+	return pt.PopulationSize
+}
+
+func (pt *PopulationTrainer) numEvaluations() int {
+	if pt.NumEvaluations <= 0 {
+		return 1
+	}
+	return pt.NumEvaluations
+}
+
+func (pt *PopulationTrainer) aggregateFitness(fitnessScores []float64) float64 {
+	if pt.FitnessAggregator == nil {
+		return AggregateMean(fitnessScores)
+	}
+	return pt.FitnessAggregator(fitnessScores)
+}
+
 func (pt *PopulationTrainer) chooseRandomOpponents(cortex *ng.Cortex, population []EvaluatedCortex, numOpponents int) (opponents []*ng.Cortex) {
 
 	if numOpponents >= len(population) {
@@ -155,9 +433,24 @@ func (pt *PopulationTrainer) sortByFitness(population EvaluatedCortexes) (sorted
 	return
 }
 
-func (pt *PopulationTrainer) exceededFitnessThreshold(evaldCortexes []EvaluatedCortex) bool {
-	for _, evaldCortex := range evaldCortexes {
+// Whether a cortex of a freshly evaluated (and therefore sorted)
+// population reaches the FitnessThreshold.  If the scape only scored it
+// on a sample, it must also reach the threshold with its FullFitness, in
+// which case it gets that fitness and is moved to the front.
+func (pt *PopulationTrainer) solve(evaldCortexes []EvaluatedCortex, scape Scape) bool {
+	sampled := isSampled(scape)
+	for i, evaldCortex := range evaldCortexes {
+		if evaldCortex.Fitness < pt.FitnessThreshold {
+			continue
+		}
+		if !sampled {
+			return true
+		}
+		evaldCortex.Fitness = boundFitness(fullFitness(scape, evaldCortex.Cortex))
+		logg.LogTo("NEURVOLVE", "Fitness of %v on all examples: %v", evaldCortex.Cortex.NodeId.UUID, evaldCortex.Fitness)
 		if evaldCortex.Fitness >= pt.FitnessThreshold {
+			copy(evaldCortexes[1:i+1], evaldCortexes[:i])
+			evaldCortexes[0] = evaldCortex
 			return true
 		}
 	}
@@ -173,12 +466,21 @@ func (pt *PopulationTrainer) cullPopulation(population []EvaluatedCortex) (culle
 	}
 
 	culledPopulationSize := len(population) / 2
-	culledPopulation = make([]EvaluatedCortex, 0)
 
-	for i, evaldCortex := range population {
-		culledPopulation = append(culledPopulation, evaldCortex)
-		if i >= (culledPopulationSize - 1) {
-			break
+	if pt.Parsimony != nil {
+		culledPopulation = pt.Parsimony.selectSurvivors(population, culledPopulationSize)
+	} else {
+		culledPopulation = truncateSelection(population, culledPopulationSize)
+	}
+
+	// forget the scores of the cortexes that didn't make the cut
+	survivors := make(map[*ng.Cortex]bool)
+	for _, evaldCortex := range culledPopulation {
+		survivors[evaldCortex.Cortex] = true
+	}
+	for _, evaldCortex := range population {
+		if evaldCortex.Cortex != nil && !survivors[evaldCortex.Cortex] {
+			delete(pt.fitnessScores, evaldCortex.Cortex)
 		}
 	}
 
@@ -198,7 +500,14 @@ func (pt *PopulationTrainer) generateOffspring(population []EvaluatedCortex) (wi
 		offspringNodeIdStr := fmt.Sprintf("cortex-%s", ng.NewUuid())
 		offspringCortex.NodeId = ng.NewCortexId(offspringNodeIdStr)
 
-		succeeded, _ := pt.CortexMutator(offspringCortex)
+		var offspringStrategy *MutationStrategy
+		var succeeded bool
+		if pt.AdaptiveCortexMutator != nil {
+			offspringStrategy = pt.offspringStrategy(evaldCortex, offspringCortex)
+			succeeded, _ = pt.AdaptiveCortexMutator(offspringCortex, offspringStrategy)
+		} else {
+			succeeded, _ = pt.CortexMutator(offspringCortex)
+		}
 		if !succeeded {
 			logg.LogPanic("Unable to mutate cortex: %v", offspringCortex)
 		}
@@ -208,6 +517,7 @@ func (pt *PopulationTrainer) generateOffspring(population []EvaluatedCortex) (wi
 			ParentId:            cortex.NodeId.UUID,
 			CreatedInGeneration: pt.CurrentGeneration,
 			Fitness:             0.0,
+			Strategy:            offspringStrategy,
 		}
 
 		withOffspring = append(withOffspring, evaldCortexOffspring)
@@ -218,6 +528,25 @@ func (pt *PopulationTrainer) generateOffspring(population []EvaluatedCortex) (wi
 
 }
 
+func (pt *PopulationTrainer) initialStrategy() *MutationStrategy {
+	if pt.InitialStrategy == nil {
+		return NewMutationStrategy(DEFAULT_STD_DEVIATION, false)
+	}
+	return pt.InitialStrategy.Copy()
+}
+
+// Copy the parent's strategy and adapt it, so that the offspring's
+// mutation intensity evolves along with its weights
+func (pt *PopulationTrainer) offspringStrategy(parent EvaluatedCortex, offspringCortex *ng.Cortex) *MutationStrategy {
+	strategy := parent.Strategy
+	if strategy == nil {
+		strategy = pt.initialStrategy()
+	}
+	strategy = strategy.Copy()
+	strategy.Adapt(offspringCortex)
+	return strategy
+}
+
 func (pt *PopulationTrainer) dumpPopulationToLog(population []EvaluatedCortex) {
 
 	for _, evaluatedCortex := range population {
@@ -199,3 +199,49 @@ func TestRetainParent(t *testing.T) {
 	}
 
 }
+
+func TestComputeFitnessAccumulate(t *testing.T) {
+
+	pt := &PopulationTrainer{
+		NumEvaluations:    2,
+		FitnessAggregator: AggregateMin,
+		AccumulateFitness: true,
+	}
+
+	cortex := SingleNeuronCortex("cortex1")
+	population := pt.addEmptyFitnessScores([]*ng.Cortex{cortex})
+
+	scape := &CountingScape{}
+	recorder := NullRecorder{}
+
+	// first generation: scores 1, 2
+	population = pt.computeFitness(population, scape, recorder)
+	assert.Equals(t, scape.numEvaluations, 2)
+	assert.Equals(t, population[0].Fitness, 1.0)
+
+	// second generation: scores 1, 2, 3, 4
+	population = pt.computeFitness(population, scape, recorder)
+	assert.Equals(t, len(pt.FitnessScores(cortex)), 4)
+	assert.Equals(t, population[0].Fitness, 1.0)
+
+	// without accumulation, only the latest scores count: 5, 6
+	pt.AccumulateFitness = false
+	population = pt.computeFitness(population, scape, recorder)
+	assert.Equals(t, len(pt.FitnessScores(cortex)), 2)
+	assert.Equals(t, population[0].Fitness, 5.0)
+
+}
+
+// Scape whose fitness is the number of times it has been evaluated
+type CountingScape struct {
+	numEvaluations int
+}
+
+func (scape *CountingScape) FitnessAgainst(cortex *ng.Cortex, opponent *ng.Cortex) float64 {
+	return scape.Fitness(cortex)
+}
+
+func (scape *CountingScape) Fitness(cortex *ng.Cortex) float64 {
+	scape.numEvaluations += 1
+	return float64(scape.numEvaluations)
+}
@@ -0,0 +1,436 @@
+package neurvolve
+
+import (
+	"github.com/couchbaselabs/logg"
+	ng "github.com/maxxk/neurgo"
+	"math"
+)
+
+type PredatorPreyRole string
+
+const (
+	ROLE_PREDATOR PredatorPreyRole = "predator"
+	ROLE_PREY     PredatorPreyRole = "prey"
+)
+
+type GridDirection int
+
+// Moves of an agent, in the order of the actuator outputs
+const (
+	GRID_STAY GridDirection = iota
+	GRID_NORTH
+	GRID_EAST
+	GRID_SOUTH
+	GRID_WEST
+)
+
+const NUM_GRID_MOVES = 5
+
+// Fitness added for each piece of food eaten by the prey
+const PREY_FOOD_REWARD = 0.25
+
+type GridPosition struct {
+	X, Y int
+}
+
+// A grid world where a predator chases a prey, which scores by eating
+// food and staying alive.  Cortexes play one of the roles, against a
+// scripted agent for Fitness and against the opponent cortex for
+// FitnessAgainst.  Both agents move at the same time, and episodes are
+// deterministic unless the agents are not.
+//
+// The cortexes have three sensors, each with one element per direction
+// in the order north, east, south, west:
+//
+//   - walls: range-finders with 1/distance to the nearest wall or edge
+//   - agents: range-finders with 1/distance to the other agent if it is
+//     in sight, otherwise 0
+//   - smell: how much stronger the scent of the prey (for the predator)
+//     or of the food (for the prey) is one step in that direction
+//
+// and an actuator of length NUM_GRID_MOVES, whose largest output is the
+// move, see GridDirection.
+type PredatorPreyScape struct {
+	Width  int
+	Height int
+	Walls  []GridPosition
+	Food   []GridPosition
+
+	PredatorStart GridPosition
+	PreyStart     GridPosition
+
+	// Length of an episode, unless the prey is caught
+	MaxSteps int
+
+	// Role of the cortex being evaluated.  Defaults to ROLE_PREDATOR.
+	Role PredatorPreyRole
+
+	// Plays the other role for Fitness.  Defaults to a FleeingGridAgent
+	// for the prey or a ChasingGridAgent for the predator.
+	Opponent GridAgent
+
+	// If set, Fitness is instead the mean fitness against each of these
+	// cortexes, which must have been evolved in the other role, eg by
+	// CoevolvePredatorPrey
+	Opponents []*ng.Cortex
+}
+
+type GridAgent interface {
+	ChooseMove(world *GridWorld, role PredatorPreyRole) GridDirection
+}
+
+// Moves to the cell closest to the prey
+type ChasingGridAgent struct{}
+
+// Moves to the cell furthest from the predator, and of those, closest
+// to the food
+type FleeingGridAgent struct{}
+
+// Plays the moves chosen by a running cortex
+type cortexGridAgent struct {
+	stepper *CortexStepper
+}
+
+// The state of an episode
+type GridWorld struct {
+	scape *PredatorPreyScape
+	walls map[GridPosition]bool
+	food  map[GridPosition]bool
+
+	Predator  GridPosition
+	Prey      GridPosition
+	Steps     int
+	FoodEaten int
+	Caught    bool
+}
+
+// A 10x10 arena with two walls and three pieces of food, where the
+// predator and prey start in opposite corners
+func NewPredatorPreyScape(role PredatorPreyRole) *PredatorPreyScape {
+	return &PredatorPreyScape{
+		Width:  10,
+		Height: 10,
+		Walls: []GridPosition{
+			{4, 2}, {4, 3}, {4, 4},
+			{6, 5}, {6, 6}, {6, 7},
+		},
+		Food:          []GridPosition{{9, 0}, {0, 9}, {5, 5}},
+		PredatorStart: GridPosition{0, 0},
+		PreyStart:     GridPosition{9, 9},
+		MaxSteps:      40,
+		Role:          role,
+	}
+}
+
+// A cortex with the sensors and actuator of a PredatorPreyScape agent,
+// where each output neuron is connected to every sensor
+func PredatorPreyCortex() *ng.Cortex {
+	factory := &PopulationFactory{
+		Sensors: []SensorSpec{
+			{Name: "walls", VectorLength: 4},
+			{Name: "agents", VectorLength: 4},
+			{Name: "smell", VectorLength: 4},
+		},
+		Actuators: []ActuatorSpec{{Name: "move", VectorLength: NUM_GRID_MOVES}},
+		Template:  TEMPLATE_MINIMAL,
+	}
+	return factory.NewCortex()
+}
+
+func (scape PredatorPreyScape) Fitness(cortex *ng.Cortex) float64 {
+	if len(scape.Opponents) > 0 {
+		fitnessScores := make([]float64, len(scape.Opponents))
+		for i, opponent := range scape.Opponents {
+			fitnessScores[i] = scape.FitnessAgainst(cortex, opponent)
+		}
+		return AggregateMean(fitnessScores)
+	}
+	agent := startCortexGridAgent(cortex)
+	defer agent.stepper.Stop()
+	return scape.fitness(agent, scape.opponent())
+}
+
+// Fitness of the cortex in the scape's role against an opponent cortex
+// playing the other role, which must come from a population evolved in
+// that role.  Since a PopulationTrainer with NumOpponents draws opponents
+// from the same population, predators and prey are coevolved with
+// CoevolvePredatorPrey instead.
+func (scape PredatorPreyScape) FitnessAgainst(cortex *ng.Cortex, opponent *ng.Cortex) float64 {
+	if cortex == opponent {
+		logg.LogPanic("Cannot play a cortex against itself")
+	}
+	agent := startCortexGridAgent(cortex)
+	defer agent.stepper.Stop()
+	opponentAgent := startCortexGridAgent(opponent)
+	defer opponentAgent.stepper.Stop()
+	return scape.fitness(agent, opponentAgent)
+}
+
+// Run an episode with the agent in the scape's role and return its fitness
+func (scape PredatorPreyScape) fitness(agent, opponent GridAgent) float64 {
+	if scape.role() == ROLE_PREDATOR {
+		return scape.RunEpisode(agent, opponent).PredatorFitness()
+	}
+	return scape.RunEpisode(opponent, agent).PreyFitness()
+}
+
+// Run an episode until the prey is caught or MaxSteps have passed
+func (scape *PredatorPreyScape) RunEpisode(predator, prey GridAgent) *GridWorld {
+	world := scape.newWorld()
+	for world.Steps < scape.MaxSteps && !world.Caught {
+		predatorMove := predator.ChooseMove(world, ROLE_PREDATOR)
+		preyMove := prey.ChooseMove(world, ROLE_PREY)
+		world.step(predatorMove, preyMove)
+	}
+	return world
+}
+
+func (scape *PredatorPreyScape) newWorld() *GridWorld {
+
+	world := &GridWorld{
+		scape:    scape,
+		walls:    make(map[GridPosition]bool),
+		food:     make(map[GridPosition]bool),
+		Predator: scape.PredatorStart,
+		Prey:     scape.PreyStart,
+	}
+	for _, wall := range scape.Walls {
+		world.walls[wall] = true
+	}
+	for _, food := range scape.Food {
+		world.food[food] = true
+	}
+
+	if scape.MaxSteps <= 0 {
+		logg.LogPanic("MaxSteps must be positive, got %d", scape.MaxSteps)
+	}
+	for _, start := range []GridPosition{scape.PredatorStart, scape.PreyStart} {
+		if world.Blocked(start) {
+			logg.LogPanic("Start position %v is outside the grid or on a wall", start)
+		}
+	}
+	return world
+
+}
+
+// Coevolve a population of predators and one of prey in the arena of the
+// scape, whose Role and Opponents are ignored.  In each of the rounds,
+// the predators are trained by their trainer against the first
+// numOpponents cortexes of the prey population, which after training are
+// its fittest survivors, and then the prey against those of the
+// predators.  Returns both populations after the last round.
+func CoevolvePredatorPrey(arena PredatorPreyScape, predatorTrainer, preyTrainer *PopulationTrainer, predators, prey []*ng.Cortex, rounds, numOpponents int) (evolvedPredators, evolvedPrey []EvaluatedCortex) {
+
+	if numOpponents <= 0 || numOpponents > len(predators) || numOpponents > len(prey) {
+		logg.LogPanic("Cannot choose %d opponents from %d predators and %d prey", numOpponents, len(predators), len(prey))
+	}
+
+	for round := 0; round < rounds; round++ {
+
+		predatorScape := arena
+		predatorScape.Role = ROLE_PREDATOR
+		predatorScape.Opponents = prey[:numOpponents]
+		evolvedPredators, _ = predatorTrainer.Train(predators, &predatorScape, NewNullRecorder())
+		predators = populationCortexes(evolvedPredators)
+
+		preyScape := arena
+		preyScape.Role = ROLE_PREY
+		preyScape.Opponents = predators[:numOpponents]
+		evolvedPrey, _ = preyTrainer.Train(prey, &preyScape, NewNullRecorder())
+		prey = populationCortexes(evolvedPrey)
+
+		logg.LogTo("NEURVOLVE", "Coevolution round %d: fittest predator %v, fittest prey %v", round, evolvedPredators[0].Fitness, evolvedPrey[0].Fitness)
+
+	}
+	return
+
+}
+
+func populationCortexes(population []EvaluatedCortex) []*ng.Cortex {
+	cortexes := make([]*ng.Cortex, len(population))
+	for i, evaldCortex := range population {
+		cortexes[i] = evaldCortex.Cortex
+	}
+	return cortexes
+}
+
+func (scape PredatorPreyScape) role() PredatorPreyRole {
+	if scape.Role == "" {
+		return ROLE_PREDATOR
+	}
+	return scape.Role
+}
+
+func (scape PredatorPreyScape) opponent() GridAgent {
+	if scape.Opponent != nil {
+		return scape.Opponent
+	}
+	if scape.role() == ROLE_PREDATOR {
+		return FleeingGridAgent{}
+	}
+	return ChasingGridAgent{}
+}
+
+// 1 plus the fraction of the episode left when the prey was caught,
+// or if it wasn't, a score below 1 which increases as the predator
+// gets closer
+func (world *GridWorld) PredatorFitness() float64 {
+	if world.Caught {
+		return 1 + float64(world.scape.MaxSteps-world.Steps)/float64(world.scape.MaxSteps)
+	}
+	return 1 / (1 + float64(manhattanDistance(world.Predator, world.Prey)))
+}
+
+// The fraction of the episode the prey survived, plus PREY_FOOD_REWARD
+// for each piece of food it ate
+func (world *GridWorld) PreyFitness() float64 {
+	return float64(world.Steps)/float64(world.scape.MaxSteps) + PREY_FOOD_REWARD*float64(world.FoodEaten)
+}
+
+// Whether the position is outside the grid or on a wall
+func (world *GridWorld) Blocked(position GridPosition) bool {
+	if position.X < 0 || position.X >= world.scape.Width || position.Y < 0 || position.Y >= world.scape.Height {
+		return true
+	}
+	return world.walls[position]
+}
+
+func (world *GridWorld) HasFood(position GridPosition) bool {
+	return world.food[position]
+}
+
+// The position of the agent playing the role, and of the other agent
+func (world *GridWorld) positions(role PredatorPreyRole) (self, other GridPosition) {
+	if role == ROLE_PREDATOR {
+		return world.Predator, world.Prey
+	}
+	return world.Prey, world.Predator
+}
+
+// Where a move from the position leads, which is the same position if
+// the move is blocked
+func (world *GridWorld) Move(position GridPosition, direction GridDirection) GridPosition {
+	moved := position.neighbor(direction)
+	if world.Blocked(moved) {
+		return position
+	}
+	return moved
+}
+
+func (world *GridWorld) step(predatorMove, preyMove GridDirection) {
+
+	predator := world.Move(world.Predator, predatorMove)
+	prey := world.Move(world.Prey, preyMove)
+	swapped := predator == world.Prey && prey == world.Predator
+
+	world.Predator, world.Prey = predator, prey
+	world.Steps += 1
+
+	if predator == prey || swapped {
+		world.Caught = true
+		return
+	}
+	if world.food[prey] {
+		delete(world.food, prey)
+		world.FoodEaten += 1
+	}
+
+}
+
+// The inputs of each sensor of the agent playing the role
+func (world *GridWorld) Sense(role PredatorPreyRole) [][]float64 {
+
+	self, other := world.positions(role)
+	walls := make([]float64, 4)
+	agents := make([]float64, 4)
+	smell := make([]float64, 4)
+
+	here := world.scent(self, role)
+	for i, direction := range []GridDirection{GRID_NORTH, GRID_EAST, GRID_SOUTH, GRID_WEST} {
+
+		distance := 1
+		position := self.neighbor(direction)
+		for !world.Blocked(position) {
+			if position == other {
+				agents[i] = 1 / float64(distance)
+			}
+			distance += 1
+			position = position.neighbor(direction)
+		}
+		walls[i] = 1 / float64(distance)
+
+		smell[i] = world.scent(world.Move(self, direction), role) - here
+
+	}
+	return [][]float64{walls, agents, smell}
+
+}
+
+// The scent at the position of what the agent playing the role is
+// looking for: the prey for the predator, the food for the prey
+func (world *GridWorld) scent(position GridPosition, role PredatorPreyRole) float64 {
+	if role == ROLE_PREDATOR {
+		return 1 / (1 + float64(manhattanDistance(position, world.Prey)))
+	}
+	scent := 0.0
+	for food := range world.food {
+		scent += 1 / (1 + float64(manhattanDistance(position, food)))
+	}
+	return scent
+}
+
+func (agent ChasingGridAgent) ChooseMove(world *GridWorld, role PredatorPreyRole) GridDirection {
+	self, other := world.positions(role)
+	return bestGridMove(world, self, func(position GridPosition) float64 {
+		return -float64(manhattanDistance(position, other))
+	})
+}
+
+func (agent FleeingGridAgent) ChooseMove(world *GridWorld, role PredatorPreyRole) GridDirection {
+	self, other := world.positions(role)
+	return bestGridMove(world, self, func(position GridPosition) float64 {
+		// the distance dominates, the scent only breaks ties
+		return float64(manhattanDistance(position, other)) + world.scent(position, ROLE_PREY)/float64(len(world.scape.Food)+1)
+	})
+}
+
+// The move leading to the position with the highest value, preferring
+// earlier moves in case of ties
+func bestGridMove(world *GridWorld, position GridPosition, value func(GridPosition) float64) GridDirection {
+	best := GRID_STAY
+	bestValue := math.Inf(-1)
+	for direction := GRID_STAY; direction < NUM_GRID_MOVES; direction++ {
+		v := value(world.Move(position, direction))
+		if v > bestValue {
+			best, bestValue = direction, v
+		}
+	}
+	return best
+}
+
+func startCortexGridAgent(cortex *ng.Cortex) *cortexGridAgent {
+	return &cortexGridAgent{stepper: StartCortex(cortex)}
+}
+
+func (agent *cortexGridAgent) ChooseMove(world *GridWorld, role PredatorPreyRole) GridDirection {
+	outputs := agent.stepper.Step(world.Sense(role))
+	return GridDirection(argmax(outputs[0]))
+}
+
+func (position GridPosition) neighbor(direction GridDirection) GridPosition {
+	switch direction {
+	case GRID_NORTH:
+		position.Y -= 1
+	case GRID_EAST:
+		position.X += 1
+	case GRID_SOUTH:
+		position.Y += 1
+	case GRID_WEST:
+		position.X -= 1
+	}
+	return position
+}
+
+func manhattanDistance(a, b GridPosition) int {
+	return int(math.Abs(float64(a.X-b.X)) + math.Abs(float64(a.Y-b.Y)))
+}
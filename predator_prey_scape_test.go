@@ -0,0 +1,227 @@
+package neurvolve
+
+import (
+	"github.com/couchbaselabs/go.assert"
+	ng "github.com/maxxk/neurgo"
+	"testing"
+)
+
+// Always makes the same move
+type ConstantGridAgent struct {
+	move GridDirection
+}
+
+func (agent ConstantGridAgent) ChooseMove(world *GridWorld, role PredatorPreyRole) GridDirection {
+	return agent.move
+}
+
+// A grid agent cortex which always makes the same move
+func ConstantMoveCortex(move GridDirection) *ng.Cortex {
+	cortex := PredatorPreyCortex()
+	for i, neuron := range cortex.Neurons {
+		neuron.ActivationFunction = ng.EncodableIdentity()
+		neuron.Bias = 0
+		if GridDirection(i) == move {
+			neuron.Bias = 1
+		}
+		for _, inbound := range neuron.Inbound {
+			for j := range inbound.Weights {
+				inbound.Weights[j] = 0
+			}
+		}
+	}
+	return cortex
+}
+
+func corridorScape() *PredatorPreyScape {
+	return &PredatorPreyScape{
+		Width:         5,
+		Height:        1,
+		Food:          []GridPosition{{3, 0}},
+		PredatorStart: GridPosition{0, 0},
+		PreyStart:     GridPosition{4, 0},
+		MaxSteps:      10,
+	}
+}
+
+func TestPredatorPreyEpisode(t *testing.T) {
+
+	scape := corridorScape()
+
+	// the prey runs into the predator, eating the food on the way
+	world := scape.RunEpisode(ConstantGridAgent{GRID_STAY}, ConstantGridAgent{GRID_WEST})
+	assert.True(t, world.Caught)
+	assert.Equals(t, world.Steps, 4)
+	assert.Equals(t, world.FoodEaten, 1)
+	assert.Equals(t, world.PredatorFitness(), 1.6)
+	assert.Equals(t, world.PreyFitness(), 0.4+PREY_FOOD_REWARD)
+
+	// agents which swap places are caught
+	world = scape.RunEpisode(ChasingGridAgent{}, ConstantGridAgent{GRID_WEST})
+	assert.True(t, world.Caught)
+	assert.Equals(t, world.Steps, 2)
+
+	// the prey is cornered
+	world = scape.RunEpisode(ChasingGridAgent{}, FleeingGridAgent{})
+	assert.True(t, world.Caught)
+	assert.Equals(t, world.Steps, 4)
+
+	// nobody moves
+	world = scape.RunEpisode(ConstantGridAgent{GRID_NORTH}, ConstantGridAgent{GRID_EAST})
+	assert.False(t, world.Caught)
+	assert.Equals(t, world.Steps, 10)
+	assert.Equals(t, world.PredatorFitness(), 0.2)
+	assert.Equals(t, world.PreyFitness(), 1.0)
+
+}
+
+func TestPredatorPreySense(t *testing.T) {
+
+	scape := &PredatorPreyScape{
+		Width:         5,
+		Height:        5,
+		Walls:         []GridPosition{{2, 1}},
+		Food:          []GridPosition{{4, 2}},
+		PredatorStart: GridPosition{2, 2},
+		PreyStart:     GridPosition{0, 2},
+		MaxSteps:      10,
+	}
+	world := scape.newWorld()
+
+	// north, east, south, west
+	inputs := world.Sense(ROLE_PREDATOR)
+	assert.Equals(t, len(inputs), 3)
+	walls, agents, smell := inputs[0], inputs[1], inputs[2]
+	assert.Equals(t, walls[0], 1.0)
+	assert.Equals(t, walls[1], 1.0/3)
+	assert.Equals(t, walls[2], 1.0/3)
+	assert.Equals(t, walls[3], 1.0/3)
+	assert.Equals(t, agents[0], 0.0)
+	assert.Equals(t, agents[3], 0.5)
+	assert.True(t, smell[3] > 0)
+	assert.True(t, smell[1] < 0)
+	assert.Equals(t, smell[0], 0.0)
+
+	inputs = world.Sense(ROLE_PREY)
+	assert.Equals(t, inputs[1][1], 0.5)
+	assert.True(t, inputs[2][1] > 0)
+	assert.True(t, inputs[2][3] == 0)
+
+}
+
+func TestPredatorPreyScapeFitness(t *testing.T) {
+
+	scape := corridorScape()
+
+	// a predator which stays put never reaches the fleeing prey
+	assert.Equals(t, scape.Fitness(ConstantMoveCortex(GRID_STAY)), 0.2)
+
+	// a predator which moves east catches it in the corner
+	assert.Equals(t, scape.Fitness(ConstantMoveCortex(GRID_EAST)), 1.6)
+
+	// a prey which stays in its corner is caught after 4 steps
+	scape.Role = ROLE_PREY
+	assert.Equals(t, scape.Fitness(ConstantMoveCortex(GRID_EAST)), 0.4)
+
+	// cortexes against each other
+	assert.Equals(t, scape.FitnessAgainst(ConstantMoveCortex(GRID_WEST), ConstantMoveCortex(GRID_STAY)), 0.4+PREY_FOOD_REWARD)
+	scape.Role = ROLE_PREDATOR
+	assert.Equals(t, scape.FitnessAgainst(ConstantMoveCortex(GRID_STAY), ConstantMoveCortex(GRID_WEST)), 1.6)
+
+}
+
+func TestPredatorPreyFitnessAgainstOppositeRole(t *testing.T) {
+
+	predatorScape := corridorScape()
+	preyScape := corridorScape()
+	preyScape.Role = ROLE_PREY
+
+	predators := []*ng.Cortex{ConstantMoveCortex(GRID_STAY), ConstantMoveCortex(GRID_EAST)}
+	preys := []*ng.Cortex{ConstantMoveCortex(GRID_WEST), ConstantMoveCortex(GRID_EAST)}
+
+	// each pairing plays the same episode from either side
+	for _, predator := range predators {
+		for _, prey := range preys {
+			predatorAgent, preyAgent := startCortexGridAgent(predator), startCortexGridAgent(prey)
+			world := predatorScape.RunEpisode(predatorAgent, preyAgent)
+			predatorAgent.stepper.Stop()
+			preyAgent.stepper.Stop()
+			assert.Equals(t, predatorScape.FitnessAgainst(predator, prey), world.PredatorFitness())
+			assert.Equals(t, preyScape.FitnessAgainst(prey, predator), world.PreyFitness())
+		}
+	}
+
+}
+
+func TestPredatorPreyOpponents(t *testing.T) {
+
+	scape := corridorScape()
+	predator := ConstantMoveCortex(GRID_STAY)
+	prey := []*ng.Cortex{ConstantMoveCortex(GRID_WEST), ConstantMoveCortex(GRID_EAST)}
+
+	scape.Opponents = prey
+	expected := (scape.FitnessAgainst(predator, prey[0]) + scape.FitnessAgainst(predator, prey[1])) / 2
+	assert.Equals(t, scape.Fitness(predator), expected)
+
+}
+
+func TestCoevolvePredatorPrey(t *testing.T) {
+
+	newTrainer := func() *PopulationTrainer {
+		return &PopulationTrainer{
+			FitnessThreshold: 10,
+			MaxGenerations:   2,
+			CortexMutator:    CombinedCortexMutator(CortexMutatorsNonTopological()...),
+			Metrics:          NewMetricsHistory(10),
+		}
+	}
+	newPopulation := func() []*ng.Cortex {
+		population := make([]*ng.Cortex, 4)
+		for i := range population {
+			population[i] = PredatorPreyCortex()
+		}
+		return population
+	}
+
+	predatorTrainer, preyTrainer := newTrainer(), newTrainer()
+	predators, prey := CoevolvePredatorPrey(*corridorScape(), predatorTrainer, preyTrainer, newPopulation(), newPopulation(), 2, 2)
+	assert.Equals(t, len(predators), 4)
+	assert.Equals(t, len(prey), 4)
+
+	// the fittest prey of the last round was scored against the
+	// fittest predators, as prey
+	scape := corridorScape()
+	scape.Role = ROLE_PREY
+	scape.Opponents = populationCortexes(predators[:2])
+	stats, _ := preyTrainer.Metrics.Latest()
+	fitness := scape.Fitness(prey[0].Cortex)
+	assert.True(t, fitness >= 0 && fitness <= 1+PREY_FOOD_REWARD)
+	assert.True(t, stats.BestFitness <= 1+PREY_FOOD_REWARD)
+
+}
+
+func TestPredatorPreyMaxSteps(t *testing.T) {
+
+	scape := corridorScape()
+	scape.MaxSteps = 0
+	defer func() {
+		assert.True(t, recover() != nil)
+	}()
+	scape.RunEpisode(ChasingGridAgent{}, FleeingGridAgent{})
+
+}
+
+func TestPredatorPreyDefaultScape(t *testing.T) {
+
+	scape := NewPredatorPreyScape(ROLE_PREDATOR)
+	world := scape.RunEpisode(ChasingGridAgent{}, FleeingGridAgent{})
+	again := scape.RunEpisode(ChasingGridAgent{}, FleeingGridAgent{})
+	assert.True(t, world.Blocked(GridPosition{4, 2}))
+	assert.Equals(t, world.Steps, again.Steps)
+	assert.Equals(t, world.Predator, again.Predator)
+	assert.Equals(t, world.Prey, again.Prey)
+
+	fitness := scape.Fitness(PredatorPreyCortex())
+	assert.True(t, fitness >= 0 && fitness < 2)
+
+}
@@ -0,0 +1,146 @@
+package neurvolve
+
+import (
+	ng "github.com/maxxk/neurgo"
+	"sort"
+)
+
+type ScapeFactory func() Scape
+type CortexFactory func() *ng.Cortex
+type RecorderFactory func() Recorder
+
+// Named components which can be referred to from an ExperimentSpec
+type Registry struct {
+	Scapes    map[string]ScapeFactory
+	Cortexes  map[string]CortexFactory
+	Mutators  map[string]CortexMutator
+	Recorders map[string]RecorderFactory
+}
+
+func NewRegistry() *Registry {
+	return &Registry{
+		Scapes:    make(map[string]ScapeFactory),
+		Cortexes:  make(map[string]CortexFactory),
+		Mutators:  make(map[string]CortexMutator),
+		Recorders: make(map[string]RecorderFactory),
+	}
+}
+
+// A registry containing all of the scapes, cortexes, mutators and
+// recorders which ship with neurvolve
+func DefaultRegistry() *Registry {
+
+	registry := NewRegistry()
+
+	registry.RegisterScape("xnor", func() Scape {
+		return &TrainingSampleScape{examples: ng.XnorTrainingSamples()}
+	})
+	registry.RegisterScape("xnor_compiled", func() Scape {
+		return &TrainingSampleScape{examples: ng.XnorTrainingSamples(), UseCompiledCortex: true}
+	})
+	registry.RegisterScape("sequence_recall", func() Scape {
+		return NewSequenceScape(RecallSequences(20, 10, 1))
+	})
+	registry.RegisterScape("sequence_parity", func() Scape {
+		return NewSequenceScape(ParitySequences(20, 10))
+	})
+	registry.RegisterScape("sine_prediction", func() Scape {
+		return NewSequenceScape(SinePredictionSequences(20, 20, 0.3))
+	})
+	registry.RegisterScape("tic_tac_toe", func() Scape {
+		return NewGameScape(TicTacToe{})
+	})
+	registry.RegisterScape("connect_four", func() Scape {
+		return NewGameScape(ConnectFour{})
+	})
+	registry.RegisterScape("grid_predator", func() Scape {
+		return NewPredatorPreyScape(ROLE_PREDATOR)
+	})
+	registry.RegisterScape("grid_prey", func() Scape {
+		return NewPredatorPreyScape(ROLE_PREY)
+	})
+	registry.RegisterScape("pole_balancing", func() Scape {
+		return NewPoleBalancingScape()
+	})
+
+	registry.RegisterCortex("xnor", ng.XnorCortexUntrained)
+	registry.RegisterCortex("basic", BasicCortex)
+	registry.RegisterCortex("basic_recurrent", BasicCortexRecurrent)
+	registry.RegisterCortex("single_neuron", func() *ng.Cortex {
+		return SingleNeuronCortex("cortex")
+	})
+	registry.RegisterCortex("tic_tac_toe", func() *ng.Cortex {
+		return GameCortex(9, 9)
+	})
+	registry.RegisterCortex("connect_four", func() *ng.Cortex {
+		return GameCortex(CONNECT_FOUR_ROWS*CONNECT_FOUR_COLUMNS, CONNECT_FOUR_COLUMNS)
+	})
+	registry.RegisterCortex("grid_agent", PredatorPreyCortex)
+	registry.RegisterCortex("pole_balancing", PoleBalancingCortex)
+
+	mutators := map[string]CortexMutator{
+		"add_bias":                 AddBias,
+		"remove_bias":              RemoveBias,
+		"mutate_weights":           MutateWeights,
+		"reset_weights":            ResetWeights,
+		"mutate_activation":        MutateActivation,
+		"add_neuron_recurrent":     AddNeuronRecurrent,
+		"add_neuron_nonrecurrent":  AddNeuronNonRecurrent,
+		"add_inlink_recurrent":     AddInlinkRecurrent,
+		"add_inlink_nonrecurrent":  AddInlinkNonRecurrent,
+		"add_outlink_recurrent":    AddOutlinkRecurrent,
+		"add_outlink_nonrecurrent": AddOutlinkNonRecurrent,
+		"outsplice_recurrent":      OutspliceRecurrent,
+		"outsplice_nonrecurrent":   OutspliceNonRecurrent,
+		"mutate_all_weights_bell":  MutateAllWeightsBellCurve,
+		"topology_or_weight":       TopologyOrWeightMutator,
+		"noop":                     NoOpMutator,
+	}
+	for name, mutator := range mutators {
+		registry.RegisterMutator(name, mutator)
+	}
+
+	registry.RegisterRecorder("null", func() Recorder {
+		return NewNullRecorder()
+	})
+
+	return registry
+
+}
+
+func (registry *Registry) RegisterScape(name string, factory ScapeFactory) {
+	registry.Scapes[name] = factory
+}
+
+func (registry *Registry) RegisterCortex(name string, factory CortexFactory) {
+	registry.Cortexes[name] = factory
+}
+
+func (registry *Registry) RegisterMutator(name string, mutator CortexMutator) {
+	registry.Mutators[name] = mutator
+}
+
+func (registry *Registry) RegisterRecorder(name string, factory RecorderFactory) {
+	registry.Recorders[name] = factory
+}
+
+// The names of all registered components, grouped by kind
+func (registry *Registry) Names() map[string][]string {
+	names := make(map[string][]string)
+	for name := range registry.Scapes {
+		names["scapes"] = append(names["scapes"], name)
+	}
+	for name := range registry.Cortexes {
+		names["cortexes"] = append(names["cortexes"], name)
+	}
+	for name := range registry.Mutators {
+		names["mutators"] = append(names["mutators"], name)
+	}
+	for name := range registry.Recorders {
+		names["recorders"] = append(names["recorders"], name)
+	}
+	for _, kindNames := range names {
+		sort.Strings(kindNames)
+	}
+	return names
+}
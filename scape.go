@@ -20,3 +20,38 @@ type Scape interface {
 	// Calculate the fitness against an actual opponent
 	FitnessAgainst(cortex *ng.Cortex, opponent *ng.Cortex) float64
 }
+
+// Scapes which change between generations, such as those scoring on a
+// mini-batch of their examples, implement this to be told when a new
+// generation starts.  The PopulationTrainer calls it before evaluating
+// each generation, with the best fitness of the previous one, or
+// negative infinity for the first.
+type GenerationalScape interface {
+	StartGeneration(generation int, bestFitness float64)
+}
+
+// Scapes which may score a generation on a sample of their examples, such
+// as a TrainingSampleScape with a MiniBatchSize, implement this so that a
+// cortex which reaches the fitness threshold on the sample is checked on
+// all of them before training is declared successful.
+type SampledScape interface {
+
+	// Whether the current generation is scored on a sample
+	Sampled() bool
+
+	// Fitness on all the examples in use rather than on the sample
+	FullFitness(cortex *ng.Cortex) float64
+}
+
+func isSampled(scape Scape) bool {
+	sampledScape, ok := scape.(SampledScape)
+	return ok && sampledScape.Sampled()
+}
+
+// The FullFitness of a SampledScape, or the Fitness of any other scape
+func fullFitness(scape Scape, cortex *ng.Cortex) float64 {
+	if sampledScape, ok := scape.(SampledScape); ok {
+		return sampledScape.FullFitness(cortex)
+	}
+	return scape.Fitness(cortex)
+}
@@ -0,0 +1,138 @@
+package neurvolve
+
+import (
+	ng "github.com/maxxk/neurgo"
+	"math"
+	"math/rand"
+)
+
+const MIN_STEP_SIZE = 1e-5
+const MAX_STEP_SIZE = 10 * math.Pi
+
+// Default multiplier used by the 1/5th success rule, as recommended by Schwefel
+const DEFAULT_STEP_SIZE_ADAPTATION_FACTOR = 0.817
+
+type AdaptiveCortexMutator func(*ng.Cortex, *MutationStrategy) (bool, MutateResult)
+
+// Strategy parameters which control how intensely a cortex is mutated.
+// They are inherited by offspring and mutated along with the cortex,
+// so that step sizes which produce fit offspring are propagated.
+type MutationStrategy struct {
+
+	// Standard deviation of the weight perturbation
+	StepSize float64
+
+	// If true, every neuron gets its own step size
+	PerNeuron bool
+
+	// Step sizes of individual neurons, keyed by neuron uuid.  Neurons
+	// which are missing (eg, they were just added by a topological
+	// mutation) use StepSize.
+	NeuronStepSizes map[string]float64
+}
+
+func NewMutationStrategy(stepSize float64, perNeuron bool) *MutationStrategy {
+	return &MutationStrategy{
+		StepSize:        stepSize,
+		PerNeuron:       perNeuron,
+		NeuronStepSizes: make(map[string]float64),
+	}
+}
+
+func (strategy *MutationStrategy) Copy() *MutationStrategy {
+	strategyCopy := NewMutationStrategy(strategy.StepSize, strategy.PerNeuron)
+	for uuid, stepSize := range strategy.NeuronStepSizes {
+		strategyCopy.NeuronStepSizes[uuid] = stepSize
+	}
+	return strategyCopy
+}
+
+func (strategy *MutationStrategy) StepSizeFor(neuron *ng.Neuron) float64 {
+	if !strategy.PerNeuron {
+		return strategy.StepSize
+	}
+	stepSize, ok := strategy.NeuronStepSizes[neuron.NodeId.UUID]
+	if !ok {
+		return strategy.StepSize
+	}
+	return stepSize
+}
+
+// Log-normal self-adaptation of the step sizes: every step size is
+// multiplied by exp(tau' * N(0,1) + tau * N_i(0,1)), where the first
+// term is shared by the whole cortex and the second is per neuron.
+func (strategy *MutationStrategy) Adapt(cortex *ng.Cortex) {
+
+	n := float64(numParameters(cortex))
+	if n < 1 {
+		n = 1
+	}
+	tauGlobal := 1 / math.Sqrt(2*n)
+	tauLocal := 1 / math.Sqrt(2*math.Sqrt(n))
+
+	stepSizeBounds := []float64{MIN_STEP_SIZE, MAX_STEP_SIZE}
+
+	globalStep := tauGlobal * rand.NormFloat64()
+	strategy.StepSize = saturate(strategy.StepSize*math.Exp(globalStep), stepSizeBounds)
+
+	if !strategy.PerNeuron {
+		return
+	}
+
+	for _, neuron := range cortex.Neurons {
+		stepSize := strategy.StepSizeFor(neuron)
+		localStep := tauLocal * rand.NormFloat64()
+		stepSize = saturate(stepSize*math.Exp(globalStep+localStep), stepSizeBounds)
+		strategy.NeuronStepSizes[neuron.NodeId.UUID] = stepSize
+	}
+
+}
+
+// Perturb all weights and biases of the cortex with a bell curve whose
+// standard deviation is given by the strategy
+func MutateAllWeightsSelfAdaptive(cortex *ng.Cortex, strategy *MutationStrategy) (success bool, result MutateResult) {
+
+	for _, neuron := range cortex.Neurons {
+
+		stdDev := strategy.StepSizeFor(neuron)
+
+		for _, inboundConnection := range neuron.Inbound {
+			weights := inboundConnection.Weights
+			for k, weight := range weights {
+				weights[k] = perturbParameterBellCurve(weight, stdDev)
+			}
+		}
+
+		neuron.Bias = perturbParameterBellCurve(neuron.Bias, stdDev)
+
+	}
+
+	success = true
+	result = "nothing"
+	return
+}
+
+// Rechenberg's 1/5th success rule: if more than a fifth of the recent
+// perturbations improved fitness, the steps are too timid and the step size
+// is increased, if fewer did, it is decreased.  The factor should be in (0, 1).
+func OneFifthSuccessRule(stepSize float64, successRate float64, factor float64) float64 {
+	switch {
+	case successRate > 0.2:
+		stepSize /= factor
+	case successRate < 0.2:
+		stepSize *= factor
+	}
+	return saturate(stepSize, []float64{MIN_STEP_SIZE, MAX_STEP_SIZE})
+}
+
+// The number of weights and biases in the cortex
+func numParameters(cortex *ng.Cortex) int {
+	numParameters := 0
+	for _, neuron := range cortex.Neurons {
+		numParameters += 1
+		for _, cxn := range neuron.Inbound {
+			numParameters += len(cxn.Weights)
+		}
+	}
+	return numParameters
+}
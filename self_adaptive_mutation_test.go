@@ -0,0 +1,79 @@
+package neurvolve
+
+import (
+	"github.com/couchbaselabs/go.assert"
+	ng "github.com/maxxk/neurgo"
+	"testing"
+)
+
+func TestOneFifthSuccessRule(t *testing.T) {
+
+	factor := 0.5
+
+	// too many successes -> bigger steps
+	assert.Equals(t, OneFifthSuccessRule(1.0, 0.5, factor), 2.0)
+
+	// too few successes -> smaller steps
+	assert.Equals(t, OneFifthSuccessRule(1.0, 0.1, factor), 0.5)
+
+	// exactly one fifth -> unchanged
+	assert.Equals(t, OneFifthSuccessRule(1.0, 0.2, factor), 1.0)
+
+	// never shrinks to zero
+	assert.Equals(t, OneFifthSuccessRule(MIN_STEP_SIZE, 0.0, factor), MIN_STEP_SIZE)
+
+}
+
+func TestMutationStrategyAdapt(t *testing.T) {
+
+	ng.SeedRandom()
+
+	cortex := BasicCortex()
+	strategy := NewMutationStrategy(1.0, true)
+
+	// neurons without their own step size use the cortex-wide one
+	neuron := cortex.Neurons[0]
+	assert.Equals(t, strategy.StepSizeFor(neuron), 1.0)
+
+	adapted := strategy.Copy()
+	adapted.Adapt(cortex)
+
+	// the original is untouched by adapting a copy
+	assert.Equals(t, strategy.StepSize, 1.0)
+	assert.Equals(t, len(strategy.NeuronStepSizes), 0)
+
+	assert.Equals(t, len(adapted.NeuronStepSizes), len(cortex.Neurons))
+	for _, neuron := range cortex.Neurons {
+		stepSize := adapted.StepSizeFor(neuron)
+		assert.True(t, stepSize >= MIN_STEP_SIZE)
+		assert.True(t, stepSize <= MAX_STEP_SIZE)
+	}
+
+}
+
+func TestGenerateOffspringAdaptive(t *testing.T) {
+
+	stepSizes := make([]float64, 0)
+	fakeAdaptiveMutator := func(cortex *ng.Cortex, strategy *MutationStrategy) (bool, MutateResult) {
+		stepSizes = append(stepSizes, strategy.StepSize)
+		return true, nil
+	}
+
+	pt := &PopulationTrainer{
+		AdaptiveCortexMutator: fakeAdaptiveMutator,
+		InitialStrategy:       NewMutationStrategy(1.0, false),
+	}
+
+	population := pt.addEmptyFitnessScores([]*ng.Cortex{BasicCortex()})
+	assert.Equals(t, population[0].Strategy.StepSize, 1.0)
+
+	withOffspring := pt.generateOffspring(population)
+	assert.Equals(t, len(withOffspring), 2)
+
+	parentStrategy := withOffspring[0].Strategy
+	offspringStrategy := withOffspring[1].Strategy
+	assert.True(t, parentStrategy != offspringStrategy)
+	assert.Equals(t, parentStrategy.StepSize, 1.0)
+	assert.Equals(t, stepSizes[0], offspringStrategy.StepSize)
+
+}
@@ -0,0 +1,164 @@
+package neurvolve
+
+import (
+	"github.com/couchbaselabs/logg"
+	ng "github.com/maxxk/neurgo"
+	"math"
+	"math/rand"
+)
+
+type SequenceScoring string
+
+const (
+	// Score every step which has expected outputs
+	SEQUENCE_SCORE_ALL_STEPS SequenceScoring = "all_steps"
+
+	// Only score the last step of each sequence
+	SEQUENCE_SCORE_FINAL_STEP SequenceScoring = "final_step"
+)
+
+// Steps which are fed to a cortex one after another.  Steps with nil
+// ExpectedOutputs are not scored, eg while a sequence to recall is
+// being presented.
+type TrainingSequence []*ng.TrainingSample
+
+// Evaluates cortexes on sequences of samples, which unlike the samples
+// of a TrainingSampleScape depend on each other, so that only recurrent
+// cortexes can remember what they need.  Recurrent state is kept from
+// one step to the next, and reset at the start of each sequence.
+type SequenceScape struct {
+	Sequences []TrainingSequence
+
+	// Defaults to SEQUENCE_SCORE_ALL_STEPS
+	Scoring SequenceScoring
+
+	// Defaults to LossSumOfSquares, see TrainingSampleScape
+	LossFunction LossFunction
+}
+
+func NewSequenceScape(sequences []TrainingSequence) *SequenceScape {
+	return &SequenceScape{Sequences: sequences}
+}
+
+func (scape SequenceScape) Fitness(cortex *ng.Cortex) float64 {
+	return FitnessFromLoss(scape.MeanLoss(cortex))
+}
+
+// Mean loss of the cortex over the scored steps of all sequences
+func (scape SequenceScape) MeanLoss(cortex *ng.Cortex) float64 {
+
+	lossFunction := scape.LossFunction
+	if lossFunction == nil {
+		lossFunction = LossSumOfSquares
+	}
+
+	scoredSteps := make([]*ng.TrainingSample, 0)
+	scoredOutputs := make([][][]float64, 0)
+	for _, sequence := range scape.Sequences {
+		// each run of the cortex starts with fresh recurrent state
+		outputs := CortexOutputs(cortex, sequence)
+		for i, step := range sequence {
+			if scape.scored(sequence, i) {
+				scoredSteps = append(scoredSteps, step)
+				scoredOutputs = append(scoredOutputs, outputs[i])
+			}
+		}
+	}
+
+	if len(scoredSteps) == 0 {
+		logg.LogPanic("No steps with expected outputs to score")
+	}
+	return weightedMeanLoss(lossFunction, scoredSteps, scoredOutputs, nil)
+
+}
+
+func (scape SequenceScape) scored(sequence TrainingSequence, step int) bool {
+	if sequence[step].ExpectedOutputs == nil {
+		return false
+	}
+	switch scape.Scoring {
+	case "", SEQUENCE_SCORE_ALL_STEPS:
+		return true
+	case SEQUENCE_SCORE_FINAL_STEP:
+		return step == len(sequence)-1
+	default:
+		logg.LogPanic("Unknown sequence scoring: %q", scape.Scoring)
+	}
+	return false
+}
+
+func (scape SequenceScape) FitnessAgainst(cortex *ng.Cortex, opponentCortex *ng.Cortex) (fitness float64) {
+	logg.LogPanic("Cannot calculate fitness against another cortex")
+	return 0.0
+}
+
+// Sequences of random bits, where the expected output of each step is
+// the input delay steps earlier.  The first delay steps are not scored.
+func RecallSequences(numSequences, length, delay int) []TrainingSequence {
+	sequences := make([]TrainingSequence, numSequences)
+	for i := range sequences {
+		bits := randomBits(length)
+		sequence := make(TrainingSequence, length)
+		for j, bit := range bits {
+			sequence[j] = &ng.TrainingSample{SampleInputs: [][]float64{{bit}}}
+			if j >= delay {
+				sequence[j].ExpectedOutputs = [][]float64{{bits[j-delay]}}
+			}
+		}
+		sequences[i] = sequence
+	}
+	return sequences
+}
+
+// Sequences of random bits, where the expected output of each step is
+// the parity of the bits so far: 1 if an odd number of them are set
+func ParitySequences(numSequences, length int) []TrainingSequence {
+	sequences := make([]TrainingSequence, numSequences)
+	for i := range sequences {
+		parity := 0.0
+		sequence := make(TrainingSequence, length)
+		for j, bit := range randomBits(length) {
+			parity = math.Abs(parity - bit)
+			sequence[j] = &ng.TrainingSample{
+				SampleInputs:    [][]float64{{bit}},
+				ExpectedOutputs: [][]float64{{parity}},
+			}
+		}
+		sequences[i] = sequence
+	}
+	return sequences
+}
+
+// Sequences of points of a sine wave scaled to [0, 1], each starting
+// at a random phase, where the expected output of each step is the next
+// point.  Each step advances the phase by stepSize radians.
+func SinePredictionSequences(numSequences, length int, stepSize float64) []TrainingSequence {
+	sequences := make([]TrainingSequence, numSequences)
+	for i := range sequences {
+		phase := rand.Float64() * 2 * math.Pi
+		sequence := make(TrainingSequence, length)
+		for j := range sequence {
+			current := phase + float64(j)*stepSize
+			sequence[j] = &ng.TrainingSample{
+				SampleInputs:    [][]float64{{scaledSine(current)}},
+				ExpectedOutputs: [][]float64{{scaledSine(current + stepSize)}},
+			}
+		}
+		sequences[i] = sequence
+	}
+	return sequences
+}
+
+func scaledSine(x float64) float64 {
+	return 0.5 + 0.5*math.Sin(x)
+}
+
+func randomBits(length int) []float64 {
+	bits := make([]float64, length)
+	for i := range bits {
+		if rand.Float64() < 0.5 {
+			bits[i] = 1
+		}
+	}
+	return bits
+}
@@ -0,0 +1,137 @@
+package neurvolve
+
+import (
+	"github.com/couchbaselabs/go.assert"
+	ng "github.com/maxxk/neurgo"
+	"math"
+	"testing"
+)
+
+// A cortex whose output is its input from the previous step, since the
+// output neuron reads the memory neuron through a recurrent connection
+func DelayCortex() *ng.Cortex {
+
+	sensor := &ng.Sensor{
+		NodeId:       ng.NewSensorId("sensor", 0.0),
+		VectorLength: 1,
+	}
+	sensor.Init()
+
+	output := &ng.Neuron{
+		ActivationFunction: ng.EncodableIdentity(),
+		NodeId:             ng.NewNeuronId("output", 0.25),
+	}
+	output.Init()
+
+	memory := &ng.Neuron{
+		ActivationFunction: ng.EncodableIdentity(),
+		NodeId:             ng.NewNeuronId("memory", 0.5),
+	}
+	memory.Init()
+
+	actuator := &ng.Actuator{
+		NodeId:       ng.NewActuatorId("actuator", 1.0),
+		VectorLength: 1,
+	}
+	actuator.Init()
+
+	sensor.ConnectOutbound(memory)
+	memory.ConnectInboundWeighted(sensor, []float64{1})
+
+	memory.ConnectOutbound(output)
+	output.ConnectInboundWeighted(memory, []float64{1})
+
+	output.ConnectOutbound(actuator)
+	actuator.ConnectInbound(output)
+
+	cortex := &ng.Cortex{
+		NodeId: ng.NewCortexId("delay-cortex"),
+	}
+	cortex.SetSensors([]*ng.Sensor{sensor})
+	cortex.SetNeurons([]*ng.Neuron{output, memory})
+	cortex.SetActuators([]*ng.Actuator{actuator})
+
+	return cortex
+
+}
+
+func step(input float64, expected ...float64) *ng.TrainingSample {
+	sample := &ng.TrainingSample{SampleInputs: [][]float64{{input}}}
+	if len(expected) > 0 {
+		sample.ExpectedOutputs = [][]float64{expected}
+	}
+	return sample
+}
+
+func TestSequenceScapeRecall(t *testing.T) {
+
+	scape := NewSequenceScape(RecallSequences(5, 8, 1))
+	scape.LossFunction = LossMeanAbsoluteError
+
+	assert.Equals(t, scape.MeanLoss(DelayCortex()), 0.0)
+	assert.Equals(t, scape.Fitness(DelayCortex()), MAX_FITNESS)
+
+	// without memory, the single neuron cortex outputs its input + 1
+	assert.True(t, scape.MeanLoss(SingleNeuronCortex("cortex")) >= 0.5)
+
+}
+
+func TestSequenceScapeResetsState(t *testing.T) {
+
+	// if the state carried over, the first step of the second
+	// sequence would output the 1 left over from the first
+	scape := NewSequenceScape([]TrainingSequence{
+		{step(1), step(1, 1)},
+		{step(0, 0), step(0, 0)},
+	})
+	assert.Equals(t, scape.MeanLoss(DelayCortex()), 0.0)
+
+}
+
+func TestSequenceScapeScoring(t *testing.T) {
+
+	// the delay cortex outputs 0, 1, 2
+	sequences := []TrainingSequence{
+		{step(1, 1), step(2, 2), step(3, 2)},
+	}
+	scape := NewSequenceScape(sequences)
+	scape.LossFunction = LossMeanAbsoluteError
+	assert.True(t, math.Abs(scape.MeanLoss(DelayCortex())-2.0/3) < 1e-12)
+
+	scape.Scoring = SEQUENCE_SCORE_FINAL_STEP
+	assert.Equals(t, scape.MeanLoss(DelayCortex()), 0.0)
+
+	sequences[0][2] = step(3, 3)
+	assert.Equals(t, scape.MeanLoss(DelayCortex()), 1.0)
+
+}
+
+func TestSequenceBenchmarks(t *testing.T) {
+
+	for _, sequence := range ParitySequences(10, 6) {
+		assert.Equals(t, len(sequence), 6)
+		ones := 0
+		for _, step := range sequence {
+			if step.SampleInputs[0][0] == 1 {
+				ones += 1
+			}
+			assert.Equals(t, step.ExpectedOutputs[0][0], float64(ones%2))
+		}
+	}
+
+	for _, sequence := range RecallSequences(10, 6, 2) {
+		assert.True(t, sequence[1].ExpectedOutputs == nil)
+		for i := 2; i < len(sequence); i++ {
+			assert.Equals(t, sequence[i].ExpectedOutputs[0][0], sequence[i-2].SampleInputs[0][0])
+		}
+	}
+
+	for _, sequence := range SinePredictionSequences(10, 6, 0.3) {
+		for i := 1; i < len(sequence); i++ {
+			value := sequence[i].SampleInputs[0][0]
+			assert.True(t, value >= 0 && value <= 1)
+			assert.True(t, math.Abs(sequence[i-1].ExpectedOutputs[0][0]-value) < 1e-12)
+		}
+	}
+
+}
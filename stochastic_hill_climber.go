@@ -12,22 +12,64 @@ type StochasticHillClimber struct {
 	MaxIterationsBeforeRestart int
 	MaxAttempts                int
 	WeightSaturationRange      []float64
+
+	// Initial maximum magnitude of a parameter perturbation.
+	// Defaults to DEFAULT_STEP_SIZE.
+	StepSize float64
+
+	// If true, the step size is adapted with the 1/5th success rule
+	// every StepSizeAdaptationWindow iterations
+	AdaptStepSize            bool
+	StepSizeAdaptationWindow int
+	StepSizeAdaptationFactor float64
+
+	// If set, the fittest cortex is published to it whenever it improves,
+	// with the total number of iterations as the generation
+	Snapshots *SnapshotStore
+
+	// If true, TrainExamples evaluates non-recurrent cortexes with a
+	// CompiledCortex, which is much faster than running them
+	UseCompiledCortex bool
+
+	// If set, the evaluations of the scape are counted against it and
+	// training stops once it is exhausted.  If it has a limit, MaxAttempts
+	// may be 0, for no limit on the number of restarts.
+	Budget *EvaluationBudget
+
+	// If true, math/rand is not reseeded on every restart, so that runs
+	// seeded by the caller are reproducible
+	DisableReseeding bool
+
+	// Tracks the run for TrainFrom
+	progress *trainProgress
 }
 
 func (shc *StochasticHillClimber) Train(cortex *ng.Cortex, scape Scape) (resultNeuralNet *ng.Cortex, fitness float64, succeeded bool) {
 
 	shc.validate()
 
+	shc.progress, scape = newTrainProgress(scape)
+	scape = shc.Budget.Scape(scape)
+
 	numAttempts := 0
 
+	stepSize := shc.initialStepSize()
+	numTrials := 0
+	numSuccesses := 0
+
 	fittestNeuralNet := cortex.Copy()
 	resultNeuralNet = cortex
 
 	// Apply NN to problem and save fitness
 	fitness = scape.Fitness(fittestNeuralNet)
 	logg.LogTo("MAIN", "Initial fitness: %v", fitness)
+	publishFittest(shc.Snapshots, 0, fittestNeuralNet, fitness)
+	shc.progress.update(resultNeuralNet, fitness)
+	numIterations := 0
 
-	if fitness > shc.FitnessThreshold {
+	if fitness >= shc.FitnessThreshold {
+		shc.Budget.recordSolution()
+		shc.progress.stop(STOP_SOLVED)
 		succeeded = true
 		return
 	}
@@ -38,11 +80,13 @@ func (shc *StochasticHillClimber) Train(cortex *ng.Cortex, scape Scape) (resultN
 		candidateNeuralNet := fittestNeuralNet.Copy()
 
 		// Perturb synaptic weights and biases
-		PerturbParameters(candidateNeuralNet, shc.WeightSaturationRange)
+		PerturbParametersWithStepSize(candidateNeuralNet, stepSize, shc.WeightSaturationRange)
+		numTrials += 1
 
 		// Re-Apply NN to problem
 		candidateFitness := scape.Fitness(candidateNeuralNet)
 		logg.LogTo("DEBUG", "candidate fitness: %v", fitness)
+		numIterations += 1
 
 		// If fitness of perturbed NN is higher, discard original NN and keep new
 		// If fitness of original is higher, discard perturbed and keep old.
@@ -53,23 +97,49 @@ func (shc *StochasticHillClimber) Train(cortex *ng.Cortex, scape Scape) (resultN
 			fittestNeuralNet = candidateNeuralNet
 			resultNeuralNet = candidateNeuralNet.Copy()
 			fitness = candidateFitness
+			numSuccesses += 1
+			publishFittest(shc.Snapshots, numIterations, fittestNeuralNet, fitness)
+			shc.progress.update(resultNeuralNet, fitness)
 		}
 
-		if candidateFitness > shc.FitnessThreshold {
-			logg.LogTo("MAIN", "candidateFitness: %v > Threshold.  Success at i=%v", candidateFitness, i)
+		if shc.AdaptStepSize && numTrials >= shc.StepSizeAdaptationWindow {
+			successRate := float64(numSuccesses) / float64(numTrials)
+			stepSize = OneFifthSuccessRule(stepSize, successRate, shc.StepSizeAdaptationFactor)
+			logg.LogTo("DEBUG", "success rate: %v new step size: %v", successRate, stepSize)
+			numTrials = 0
+			numSuccesses = 0
+		}
+
+		if candidateFitness >= shc.FitnessThreshold {
+			logg.LogTo("MAIN", "candidateFitness: %v >= Threshold.  Success at i=%v", candidateFitness, i)
+			shc.Budget.recordSolution()
+			shc.progress.stop(STOP_SOLVED)
 			succeeded = true
 			break
 		}
 
+		if shc.Budget.Exhausted() {
+			logg.LogTo("MAIN", "Evaluation budget exhausted.  fitness: %f", fitness)
+			shc.progress.stop(STOP_BUDGET_EXHAUSTED)
+			succeeded = false
+			break
+		}
+
 		if ng.IntModuloProper(i, shc.MaxIterationsBeforeRestart) {
 			logg.LogTo("MAIN", "** restart hill climber.  fitness: %f i/max: %d/%d", fitness, numAttempts, shc.MaxAttempts)
 			numAttempts += 1
 			i = 0
 			shc.resetParametersToRandom(fittestNeuralNet)
-			ng.SeedRandom()
+			if !shc.DisableReseeding {
+				ng.SeedRandom()
+			}
+			stepSize = shc.initialStepSize()
+			numTrials = 0
+			numSuccesses = 0
 		}
 
-		if numAttempts >= shc.MaxAttempts {
+		if shc.exceededMaxAttempts(numAttempts) {
+			shc.progress.stop(STOP_MAX_ITERATIONS)
 			succeeded = false
 			break
 		}
@@ -80,10 +150,32 @@ func (shc *StochasticHillClimber) Train(cortex *ng.Cortex, scape Scape) (resultN
 
 }
 
+// Hill climb from a single cortex made by newCortex
+func (shc *StochasticHillClimber) TrainFrom(newCortex CortexFactory, scape Scape) TrainResult {
+	_, _, succeeded := shc.Train(newCortex(), scape)
+	return shc.progress.finish(succeeded)
+}
+
+func (shc *StochasticHillClimber) GetPopulationSnapshot() *PopulationSnapshot {
+	return shc.Snapshots.Latest()
+}
+
+// Publish a population consisting of just the fittest cortex, whose
+// fitness is bounded so that it can be encoded as json
+func publishFittest(snapshots *SnapshotStore, generation int, cortex *ng.Cortex, fitness float64) {
+	evaldCortex := EvaluatedCortex{
+		Cortex:   cortex,
+		Fitness:  boundFitness(fitness),
+		ParentId: cortex.NodeId.UUID,
+	}
+	snapshots.Publish(generation, []EvaluatedCortex{evaldCortex})
+}
+
 func (shc *StochasticHillClimber) TrainExamples(cortex *ng.Cortex, examples []*ng.TrainingSample) (fittestNeuralNet *ng.Cortex, fitness float64, succeeded bool) {
 
 	trainingSampleScape := &TrainingSampleScape{
-		examples: examples,
+		examples:          examples,
+		UseCompiledCortex: shc.UseCompiledCortex,
 	}
 	return shc.Train(cortex, trainingSampleScape)
 
@@ -94,15 +186,21 @@ func (shc *StochasticHillClimber) TrainExamples(cortex *ng.Cortex, examples []*n
 // 2. Within the chosen neuron, the weights which will be perturbed will be chosen
 //    with probability of 1/sqrt(parameters_size)
 // 3. The intensity of the parameter perturbation will chosen with uniform distribution
-//    of -pi and pi
+//    of -2pi and 2pi
 func PerturbParameters(cortex *ng.Cortex, saturationBounds []float64) {
+	PerturbParametersWithStepSize(cortex, DEFAULT_STEP_SIZE, saturationBounds)
+}
+
+// Same as PerturbParameters, but the intensity of the perturbation is
+// chosen with uniform distribution of -stepSize and stepSize
+func PerturbParametersWithStepSize(cortex *ng.Cortex, stepSize float64, saturationBounds []float64) {
 
 	// pick the neurons to perturb (at least one)
 	neurons := chooseNeuronsToPerturb(cortex)
 
 	for _, neuron := range neurons {
 		logg.LogTo("DEBUG", "Going to perturb neuron: %v", neuron.NodeId.UUID)
-		perturbNeuron(neuron, saturationBounds)
+		perturbNeuron(neuron, stepSize, saturationBounds)
 	}
 
 }
@@ -152,7 +250,7 @@ func nodePerturbProbability(cortex *ng.Cortex) float64 {
 	return float64(1) / math.Log(1+numNeurons) / numNeurons
 }
 
-func perturbNeuron(neuron *ng.Neuron, saturationBounds []float64) {
+func perturbNeuron(neuron *ng.Neuron, stepSize float64, saturationBounds []float64) {
 
 	probability := parameterPerturbProbability(neuron)
 
@@ -160,10 +258,10 @@ func perturbNeuron(neuron *ng.Neuron, saturationBounds []float64) {
 	for {
 		didPerturbWeight := false
 		for _, cxn := range neuron.Inbound {
-			didPerturbWeight = possiblyPerturbConnection(cxn, probability, saturationBounds)
+			didPerturbWeight = possiblyPerturbConnection(cxn, probability, stepSize, saturationBounds)
 		}
 
-		didPerturbBias := possiblyPerturbBias(neuron, probability, saturationBounds)
+		didPerturbBias := possiblyPerturbBias(neuron, probability, stepSize, saturationBounds)
 
 		// did we perturb anything?  if so, we're done
 		if didPerturbWeight || didPerturbBias {
@@ -182,12 +280,12 @@ func parameterPerturbProbability(neuron *ng.Neuron) float64 {
 	return 1 / math.Sqrt(float64(numWeights))
 }
 
-func possiblyPerturbConnection(cxn *ng.InboundConnection, probability float64, saturationBounds []float64) bool {
+func possiblyPerturbConnection(cxn *ng.InboundConnection, probability float64, stepSize float64, saturationBounds []float64) bool {
 
 	didPerturb := false
 	for j, weight := range cxn.Weights {
 		if rand.Float64() < probability {
-			perturbedWeight := perturbParameter(weight, saturationBounds)
+			perturbedWeight := perturbParameter(weight, stepSize, saturationBounds)
 			logg.LogTo("DEBUG", "weight %v -> %v", weight, perturbedWeight)
 			cxn.Weights[j] = perturbedWeight
 			didPerturb = true
@@ -197,11 +295,11 @@ func possiblyPerturbConnection(cxn *ng.InboundConnection, probability float64, s
 
 }
 
-func possiblyPerturbBias(neuron *ng.Neuron, probability float64, saturationBounds []float64) bool {
+func possiblyPerturbBias(neuron *ng.Neuron, probability float64, stepSize float64, saturationBounds []float64) bool {
 	didPerturb := false
 	if rand.Float64() < probability {
 		bias := neuron.Bias
-		perturbedBias := perturbParameter(bias, saturationBounds)
+		perturbedBias := perturbParameter(bias, stepSize, saturationBounds)
 		neuron.Bias = perturbedBias
 		logg.LogTo("DEBUG", "bias %v -> %v", bias, perturbedBias)
 		didPerturb = true
@@ -209,8 +307,35 @@ func possiblyPerturbBias(neuron *ng.Neuron, probability float64, saturationBound
 	return didPerturb
 }
 
+// MaxAttempts only limits training without a limited budget, or if it
+// is set
+func (shc *StochasticHillClimber) exceededMaxAttempts(numAttempts int) bool {
+	if shc.Budget.limited() && shc.MaxAttempts <= 0 {
+		return false
+	}
+	return numAttempts >= shc.MaxAttempts
+}
+
+func (shc *StochasticHillClimber) initialStepSize() float64 {
+	if shc.StepSize <= 0 {
+		return DEFAULT_STEP_SIZE
+	}
+	return shc.StepSize
+}
+
 func (shc *StochasticHillClimber) validate() {
 	if len(shc.WeightSaturationRange) == 0 {
 		logg.LogPanic("Invalid (empty) WeightSaturationRange")
 	}
+	if shc.AdaptStepSize {
+		if shc.StepSizeAdaptationWindow <= 0 {
+			logg.LogPanic("Invalid StepSizeAdaptationWindow: %v", shc.StepSizeAdaptationWindow)
+		}
+		if shc.StepSizeAdaptationFactor == 0 {
+			shc.StepSizeAdaptationFactor = DEFAULT_STEP_SIZE_ADAPTATION_FACTOR
+		}
+		if shc.StepSizeAdaptationFactor <= 0 || shc.StepSizeAdaptationFactor >= 1 {
+			logg.LogPanic("Invalid StepSizeAdaptationFactor: %v", shc.StepSizeAdaptationFactor)
+		}
+	}
 }
@@ -6,6 +6,8 @@ import (
 	"github.com/couchbaselabs/logg"
 	ng "github.com/maxxk/neurgo"
 	"log"
+	"math"
+	"math/rand"
 	"testing"
 )
 
@@ -50,3 +52,23 @@ func DisabledTestUnmarshalCortexFitness(t *testing.T) {
 	log.Printf("Final fitness: %v", fitness)
 
 }
+
+func TestStochasticHillClimberDisableReseeding(t *testing.T) {
+
+	// the state of math/rand after training, when it was seeded with 1
+	trainSeeded := func() int64 {
+		rand.Seed(1)
+		shc := &StochasticHillClimber{
+			FitnessThreshold:           math.Inf(1),
+			MaxIterationsBeforeRestart: 2,
+			MaxAttempts:                100,
+			WeightSaturationRange:      []float64{-10 * math.Pi, 10 * math.Pi},
+			Budget:                     &EvaluationBudget{MaxEvaluations: 10},
+			DisableReseeding:           true,
+		}
+		shc.Train(BasicCortex(), ConstantScape{1})
+		return rand.Int63()
+	}
+	assert.Equals(t, trainSeeded(), trainSeeded())
+
+}
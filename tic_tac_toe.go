@@ -0,0 +1,86 @@
+package neurvolve
+
+// Tic-tac-toe for cortexes with a sensor and an actuator of length 9,
+// one element per square in row major order.  Squares are encoded as
+// 1 for the player to move, -1 for the opponent and 0 if empty, and a
+// move is the index of a square.
+type TicTacToe struct{}
+
+type ticTacToeState struct {
+	// 0 for empty, otherwise the player who owns the square + 1
+	board    [9]int
+	toMove   int
+	numMoves int
+}
+
+var ticTacToeLines = [][3]int{
+	{0, 1, 2}, {3, 4, 5}, {6, 7, 8},
+	{0, 3, 6}, {1, 4, 7}, {2, 5, 8},
+	{0, 4, 8}, {2, 4, 6},
+}
+
+func (game TicTacToe) InitialState() GameState {
+	return ticTacToeState{}
+}
+
+func (game TicTacToe) PlayerToMove(state GameState) int {
+	return state.(ticTacToeState).toMove
+}
+
+func (game TicTacToe) Encode(state GameState) [][]float64 {
+	s := state.(ticTacToeState)
+	return [][]float64{encodeBoard(s.board[:], s.toMove)}
+}
+
+func (game TicTacToe) LegalMoves(state GameState) []int {
+	s := state.(ticTacToeState)
+	moves := make([]int, 0)
+	for square, owner := range s.board {
+		if owner == 0 {
+			moves = append(moves, square)
+		}
+	}
+	return moves
+}
+
+func (game TicTacToe) DecodeMove(state GameState, outputs [][]float64) int {
+	return DecodeBestLegalMove(outputs[0], game.LegalMoves(state))
+}
+
+func (game TicTacToe) Play(state GameState, move int) GameState {
+	s := state.(ticTacToeState)
+	s.board[move] = s.toMove + 1
+	s.toMove = 1 - s.toMove
+	s.numMoves += 1
+	return s
+}
+
+func (game TicTacToe) Score(state GameState) (over bool, scores [2]float64) {
+	s := state.(ticTacToeState)
+	for _, line := range ticTacToeLines {
+		owner := s.board[line[0]]
+		if owner != 0 && s.board[line[1]] == owner && s.board[line[2]] == owner {
+			return true, gameScores(owner - 1)
+		}
+	}
+	if s.numMoves == len(s.board) {
+		return true, gameScores(-1)
+	}
+	return false, scores
+}
+
+// Encode squares owned by player + 1 as 1, by the other player as -1
+// and empty squares as 0
+func encodeBoard(board []int, player int) []float64 {
+	encoded := make([]float64, len(board))
+	for i, owner := range board {
+		switch owner {
+		case 0:
+		case player + 1:
+			encoded[i] = 1
+		default:
+			encoded[i] = -1
+		}
+	}
+	return encoded
+}
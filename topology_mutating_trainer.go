@@ -1,7 +1,6 @@
 package neurvolve
 
 import (
-	"fmt"
 	"github.com/couchbaselabs/logg"
 	ng "github.com/maxxk/neurgo"
 )
@@ -10,16 +9,53 @@ type TopologyMutatingTrainer struct {
 	MaxIterationsBeforeRestart int
 	MaxAttempts                int
 	StochasticHillClimber      *StochasticHillClimber
+
+	// Topological mutators to choose from.  Defaults to
+	// CortexMutatorsNonRecurrent without the non-topological ones.
+	Mutators []CortexMutator
+
+	// If set, the result of each memetic step is published to it
+	Snapshots *SnapshotStore
+
+	// If set, the result of each memetic step is saved to it, using
+	// the attempt number as its generation
+	Artifacts ArtifactStore
+
+	// If set, the evaluations of the scape, including those of the hill
+	// climber, are counted against it and training stops once it is
+	// exhausted.  If it has a limit, MaxAttempts may be 0, for no limit
+	// on the number of memetic steps.
+	Budget *EvaluationBudget
+
+	// If true, math/rand is not reseeded at the start of training, so
+	// that runs seeded by the caller are reproducible.  The hill climber
+	// has its own setting.
+	DisableReseeding bool
+
+	// Tracks the run for TrainFrom
+	progress *trainProgress
 }
 
 func (tmt *TopologyMutatingTrainer) Train(cortex *ng.Cortex, scape Scape) (fittestCortex *ng.Cortex, succeeded bool) {
 
-	ng.SeedRandom()
+	if !tmt.DisableReseeding {
+		ng.SeedRandom()
+	}
 
 	shc := tmt.StochasticHillClimber
+	if tmt.Budget != nil {
+		budgetedShc := *shc
+		budgetedShc.Budget = tmt.Budget
+		shc = &budgetedShc
+	}
+	tmt.progress, scape = newTrainProgress(scape)
+	scape = tmt.Budget.Scape(scape)
 
-	includeNonTopological := false
-	mutators := CortexMutatorsNonRecurrent(includeNonTopological)
+	mutators := tmt.Mutators
+	if len(mutators) == 0 {
+		includeNonTopological := false
+		mutators = CortexMutatorsNonRecurrent(includeNonTopological)
+	}
 
 	originalCortex := cortex.Copy()
 
@@ -29,8 +65,11 @@ func (tmt *TopologyMutatingTrainer) Train(cortex *ng.Cortex, scape Scape) (fitte
 	logg.LogTo("MAIN", "Get initial fitness")
 	fitness := scape.Fitness(currentCortex)
 	logg.LogTo("MAIN", "Initial fitness: %v", fitness)
+	tmt.progress.update(currentCortex.Copy(), fitness)
 
-	if fitness > shc.FitnessThreshold {
+	if fitness >= shc.FitnessThreshold {
+		tmt.Budget.recordSolution()
+		tmt.progress.stop(STOP_SOLVED)
 		succeeded = true
 		return
 	}
@@ -58,24 +97,32 @@ func (tmt *TopologyMutatingTrainer) Train(cortex *ng.Cortex, scape Scape) (fitte
 			logg.LogPanic("Cortex did not validate")
 		}
 
-		filenameJson := fmt.Sprintf("cortex-%v.json", i)
-		currentCortex.MarshalJSONToFile(filenameJson)
-		filenameSvg := fmt.Sprintf("cortex-%v.svg", i)
-		currentCortex.RenderSVGFile(filenameSvg)
-		logg.LogTo("MAIN", "Post mutate cortex svg: %v json: %v", filenameSvg, filenameJson)
-
 		logg.LogTo("MAIN", "Run stochastic hill climber..")
 
 		// memetic step: call stochastic hill climber and see if it can solve it
-		fittestCortex, _, succeeded = shc.Train(currentCortex, scape)
+		var fittestFitness float64
+		fittestCortex, fittestFitness, succeeded = shc.Train(currentCortex, scape)
 		logg.LogTo("MAIN", "stochastic hill climber finished.  succeeded: %v", succeeded)
+		publishFittest(tmt.Snapshots, i, fittestCortex, fittestFitness)
+		fittest := EvaluatedCortex{Cortex: fittestCortex, Fitness: fittestFitness}
+		saveArtifact(tmt.Artifacts, fittest, i)
+		// copied, since it may be the cortex which is mutated next
+		tmt.progress.update(fittestCortex.Copy(), fittestFitness)
 
 		if succeeded {
+			tmt.progress.stop(STOP_SOLVED)
 			succeeded = true
 			break
 		}
 
-		if i >= tmt.MaxAttempts {
+		if tmt.Budget.Exhausted() {
+			tmt.progress.stop(STOP_BUDGET_EXHAUSTED)
+			succeeded = false
+			break
+		}
+
+		if tmt.exceededMaxAttempts(i) {
+			tmt.progress.stop(STOP_MAX_ITERATIONS)
 			succeeded = false
 			break
 		}
@@ -101,10 +148,34 @@ func (tmt *TopologyMutatingTrainer) Train(cortex *ng.Cortex, scape Scape) (fitte
 
 }
 
+// MaxAttempts only limits training without a limited budget, or if it
+// is set
+func (tmt *TopologyMutatingTrainer) exceededMaxAttempts(attempt int) bool {
+	if tmt.Budget.limited() && tmt.MaxAttempts <= 0 {
+		return false
+	}
+	return attempt >= tmt.MaxAttempts
+}
+
+// Mutate the topology of a single cortex made by newCortex
+func (tmt *TopologyMutatingTrainer) TrainFrom(newCortex CortexFactory, scape Scape) TrainResult {
+	_, succeeded := tmt.Train(newCortex(), scape)
+	return tmt.progress.finish(succeeded)
+}
+
+func (tmt *TopologyMutatingTrainer) GetPopulationSnapshot() *PopulationSnapshot {
+	return tmt.Snapshots.Latest()
+}
+
+func (tmt *TopologyMutatingTrainer) GetArtifactStore() ArtifactStore {
+	return tmt.Artifacts
+}
+
 func (tmt *TopologyMutatingTrainer) TrainExamples(cortex *ng.Cortex, examples []*ng.TrainingSample) (fittestCortex *ng.Cortex, succeeded bool) {
 
 	trainingSampleScape := &TrainingSampleScape{
-		examples: examples,
+		examples:          examples,
+		UseCompiledCortex: tmt.StochasticHillClimber.UseCompiledCortex,
 	}
 	return tmt.Train(cortex, trainingSampleScape)
 
@@ -0,0 +1,95 @@
+package neurvolve
+
+import (
+	ng "github.com/maxxk/neurgo"
+)
+
+type StopReason string
+
+const (
+	STOP_SOLVED           StopReason = "solved"
+	STOP_MAX_ITERATIONS   StopReason = "max_iterations"
+	STOP_BUDGET_EXHAUSTED StopReason = "budget_exhausted"
+	STOP_STOPPED          StopReason = "stopped"
+)
+
+// Implemented by the PopulationTrainer, StochasticHillClimber and
+// TopologyMutatingTrainer, so that tools can run any of them
+type Trainer interface {
+
+	// Train on the scape, starting from cortexes made by newCortex
+	TrainFrom(newCortex CortexFactory, scape Scape) TrainResult
+}
+
+var (
+	_ Trainer = (*PopulationTrainer)(nil)
+	_ Trainer = (*StochasticHillClimber)(nil)
+	_ Trainer = (*TopologyMutatingTrainer)(nil)
+)
+
+type TrainResult struct {
+
+	// The fittest cortex and its fitness
+	Cortex  *ng.Cortex
+	Fitness float64
+
+	Succeeded  bool
+	StopReason StopReason
+
+	// Number of calls to Fitness and FitnessAgainst of the scape
+	Evaluations int
+
+	// The fitness of the fittest cortex each time it improved
+	History []FitnessPoint
+}
+
+type FitnessPoint struct {
+	Evaluations int
+	Fitness     float64
+}
+
+// Counts the evaluations of a training run and keeps track of its
+// fittest cortex
+type trainProgress struct {
+	counter *EvaluationBudget
+	result  TrainResult
+}
+
+// Start tracking a training run, which must evaluate cortexes with the
+// returned scape
+func newTrainProgress(scape Scape) (*trainProgress, Scape) {
+	progress := &trainProgress{counter: &EvaluationBudget{}}
+	return progress, progress.counter.Scape(scape)
+}
+
+// Record the cortex if it is fitter than the fittest one so far
+func (progress *trainProgress) update(cortex *ng.Cortex, fitness float64) {
+	result := &progress.result
+	if result.Cortex != nil && fitness <= result.Fitness {
+		return
+	}
+	result.Cortex = cortex
+	result.Fitness = fitness
+	point := FitnessPoint{Evaluations: progress.counter.Evaluations(), Fitness: fitness}
+	result.History = append(result.History, point)
+}
+
+// Record the cortex which reached the fitness threshold, even if a fitter
+// one was seen, eg on a mini-batch which flattered it
+func (progress *trainProgress) solve(cortex *ng.Cortex, fitness float64) {
+	progress.update(cortex, fitness)
+	progress.result.Cortex = cortex
+	progress.result.Fitness = fitness
+	progress.stop(STOP_SOLVED)
+}
+
+func (progress *trainProgress) stop(reason StopReason) {
+	progress.result.StopReason = reason
+}
+
+func (progress *trainProgress) finish(succeeded bool) TrainResult {
+	result := progress.result
+	result.Succeeded = succeeded
+	result.Evaluations = progress.counter.Evaluations()
+	return result
+}
@@ -0,0 +1,62 @@
+package neurvolve
+
+import (
+	"github.com/couchbaselabs/go.assert"
+	ng "github.com/maxxk/neurgo"
+	"math"
+	"testing"
+)
+
+func TestPopulationTrainerTrainFrom(t *testing.T) {
+
+	pt := &PopulationTrainer{
+		FitnessThreshold: 1000,
+		MaxGenerations:   3,
+		CortexMutator:    NoOpMutator,
+		PopulationSize:   4,
+	}
+	newCortex := func() *ng.Cortex {
+		return SingleNeuronCortex("cortex")
+	}
+
+	// the fitness is the number of evaluations so far, so the fittest
+	// cortex of each generation is the last one evaluated
+	result := pt.TrainFrom(newCortex, &CountingScape{})
+	assert.False(t, result.Succeeded)
+	assert.Equals(t, result.StopReason, STOP_MAX_ITERATIONS)
+	assert.Equals(t, result.Evaluations, 12)
+	assert.Equals(t, result.Fitness, 12.0)
+	assert.True(t, result.Cortex != nil)
+
+	assert.Equals(t, len(result.History), 3)
+	for i, point := range result.History {
+		assert.Equals(t, point.Evaluations, 4*(i+1))
+		assert.Equals(t, point.Fitness, float64(4*(i+1)))
+	}
+
+}
+
+func TestStochasticHillClimberTrainFrom(t *testing.T) {
+
+	shc := &StochasticHillClimber{
+		FitnessThreshold:           1,
+		MaxIterationsBeforeRestart: 3,
+		MaxAttempts:                1,
+		WeightSaturationRange:      []float64{-10 * math.Pi, 10 * math.Pi},
+	}
+	result := shc.TrainFrom(BasicCortex, ConstantScape{1})
+	assert.True(t, result.Succeeded)
+	assert.Equals(t, result.StopReason, STOP_SOLVED)
+	assert.Equals(t, result.Evaluations, 1)
+	assert.Equals(t, len(result.History), 1)
+
+	shc.FitnessThreshold = math.Inf(1)
+	shc.MaxAttempts = 0
+	shc.Budget = &EvaluationBudget{MaxEvaluations: 5}
+	result = shc.TrainFrom(BasicCortex, ConstantScape{1})
+	assert.False(t, result.Succeeded)
+	assert.Equals(t, result.StopReason, STOP_BUDGET_EXHAUSTED)
+	assert.Equals(t, result.Evaluations, 5)
+	assert.Equals(t, result.Fitness, 1.0)
+
+}
@@ -0,0 +1,119 @@
+package neurvolve
+
+import (
+	ng "github.com/maxxk/neurgo"
+	"sync"
+)
+
+// Lets another goroutine (eg, an http handler) pause, resume and stop a
+// running PopulationTrainer, change its settings and inject cortexes into
+// its population.  All requests take effect at the next generation boundary.
+type TrainingControl struct {
+	mutex      sync.Mutex
+	resumed    *sync.Cond
+	paused     bool
+	stopped    bool
+	generation int
+
+	fitnessThreshold *float64
+	maxGenerations   *int
+	injected         []*ng.Cortex
+}
+
+type TrainingStatus struct {
+	Paused            bool
+	Stopped           bool
+	Generation        int
+	PendingInjections int
+}
+
+// Changes requested since the last generation boundary
+type controlUpdate struct {
+	stop             bool
+	fitnessThreshold *float64
+	maxGenerations   *int
+	injected         []*ng.Cortex
+}
+
+func NewTrainingControl() *TrainingControl {
+	control := &TrainingControl{}
+	control.resumed = sync.NewCond(&control.mutex)
+	return control
+}
+
+func (control *TrainingControl) Pause() {
+	control.mutex.Lock()
+	defer control.mutex.Unlock()
+	control.paused = true
+}
+
+func (control *TrainingControl) Resume() {
+	control.mutex.Lock()
+	defer control.mutex.Unlock()
+	control.paused = false
+	control.resumed.Broadcast()
+}
+
+// Stop the trainer at the next generation boundary, even if it is paused
+func (control *TrainingControl) Stop() {
+	control.mutex.Lock()
+	defer control.mutex.Unlock()
+	control.stopped = true
+	control.resumed.Broadcast()
+}
+
+func (control *TrainingControl) SetFitnessThreshold(fitnessThreshold float64) {
+	control.mutex.Lock()
+	defer control.mutex.Unlock()
+	control.fitnessThreshold = &fitnessThreshold
+}
+
+func (control *TrainingControl) SetMaxGenerations(maxGenerations int) {
+	control.mutex.Lock()
+	defer control.mutex.Unlock()
+	control.maxGenerations = &maxGenerations
+}
+
+// Add a cortex to the population, where it replaces an unevaluated offspring
+func (control *TrainingControl) Inject(cortex *ng.Cortex) {
+	control.mutex.Lock()
+	defer control.mutex.Unlock()
+	control.injected = append(control.injected, cortex)
+}
+
+func (control *TrainingControl) Status() TrainingStatus {
+	control.mutex.Lock()
+	defer control.mutex.Unlock()
+	return TrainingStatus{
+		Paused:            control.paused,
+		Stopped:           control.stopped,
+		Generation:        control.generation,
+		PendingInjections: len(control.injected),
+	}
+}
+
+// Called by the trainer at each generation boundary.  Blocks while paused,
+// and returns the changes which were requested since the last call.
+func (control *TrainingControl) checkpoint(generation int) controlUpdate {
+
+	control.mutex.Lock()
+	defer control.mutex.Unlock()
+
+	control.generation = generation
+
+	for control.paused && !control.stopped {
+		control.resumed.Wait()
+	}
+
+	update := controlUpdate{
+		stop:             control.stopped,
+		fitnessThreshold: control.fitnessThreshold,
+		maxGenerations:   control.maxGenerations,
+		injected:         control.injected,
+	}
+	control.fitnessThreshold = nil
+	control.maxGenerations = nil
+	control.injected = nil
+	return update
+
+}
@@ -0,0 +1,102 @@
+package neurvolve
+
+import (
+	"github.com/couchbaselabs/go.assert"
+	ng "github.com/maxxk/neurgo"
+	"testing"
+	"time"
+)
+
+func TestTrainingControlPauseResume(t *testing.T) {
+
+	control := NewTrainingControl()
+	control.Pause()
+
+	checkpointed := make(chan controlUpdate)
+	go func() {
+		checkpointed <- control.checkpoint(3)
+	}()
+
+	select {
+	case <-checkpointed:
+		t.Fatalf("checkpoint should block while paused")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	control.SetMaxGenerations(10)
+	control.Resume()
+
+	update := <-checkpointed
+	assert.False(t, update.stop)
+	assert.Equals(t, *update.maxGenerations, 10)
+	assert.Equals(t, control.Status().Generation, 3)
+
+	// changes are only delivered once
+	update = control.checkpoint(4)
+	assert.True(t, update.maxGenerations == nil)
+
+}
+
+func TestTrainingControlStopWhilePaused(t *testing.T) {
+
+	control := NewTrainingControl()
+	control.Pause()
+
+	checkpointed := make(chan controlUpdate)
+	go func() {
+		checkpointed <- control.checkpoint(0)
+	}()
+
+	control.Stop()
+	update := <-checkpointed
+	assert.True(t, update.stop)
+
+}
+
+func TestApplyControl(t *testing.T) {
+
+	pt := &PopulationTrainer{
+		FitnessThreshold: 100,
+		Control:          NewTrainingControl(),
+	}
+
+	population := []EvaluatedCortex{
+		{Cortex: SingleNeuronCortex("survivor"), Fitness: 10},
+		{Cortex: SingleNeuronCortex("offspring"), Fitness: 0},
+	}
+
+	injected := SingleNeuronCortex("injected")
+	pt.Control.Inject(injected)
+	pt.Control.SetFitnessThreshold(50)
+
+	population, stop := pt.applyControl(population)
+	assert.False(t, stop)
+	assert.Equals(t, pt.FitnessThreshold, 50.0)
+	assert.Equals(t, len(population), 2)
+	assert.Equals(t, population[0].Cortex.NodeId.UUID, "survivor")
+	assert.Equals(t, population[1].Cortex, injected)
+	assert.Equals(t, population[1].ParentId, "injected")
+
+	pt.Control.Stop()
+	_, stop = pt.applyControl(population)
+	assert.True(t, stop)
+
+}
+
+func TestTrainStopped(t *testing.T) {
+
+	pt := &PopulationTrainer{
+		FitnessThreshold: 1000,
+		MaxGenerations:   1000000,
+		Control:          NewTrainingControl(),
+	}
+	pt.Control.Stop()
+
+	scape := &CountingScape{}
+	population := []*ng.Cortex{SingleNeuronCortex("cortex1"), SingleNeuronCortex("cortex2")}
+	trainedPopulation, succeeded := pt.Train(population, scape, NewNullRecorder())
+	assert.False(t, succeeded)
+	assert.Equals(t, len(trainedPopulation), 2)
+	assert.Equals(t, scape.numEvaluations, 0)
+
+}
@@ -3,18 +3,262 @@ package neurvolve
 import (
 	"github.com/couchbaselabs/logg"
 	ng "github.com/maxxk/neurgo"
+	"math/rand"
+	"sort"
 )
 
+// Scores cortexes on training samples.  Since StartGeneration picks the
+// examples of each generation, it is only a Scape through a pointer, as
+// returned by NewTrainingSampleScape.
 type TrainingSampleScape struct {
 	examples []*ng.TrainingSample
+
+	// If true, non-recurrent cortexes are evaluated with a CompiledCortex
+	// rather than by running them.  Recurrent cortexes are still run.
+	// Compiled cortexes are cached, so a cortex evaluated again is only
+	// compiled again if it changed.
+	UseCompiledCortex bool
+
+	// How the outputs of the cortex are scored against the expected
+	// outputs.  The fitness is FitnessFromLoss of the mean loss.
+	// Defaults to LossSumOfSquares, which is what ng.Cortex.Fitness uses.
+	LossFunction LossFunction
+
+	// Weight of each example in the mean loss, defaults to 1 for all
+	SampleWeights []float64
+
+	// If positive, each generation is scored on a random mini-batch of
+	// this many examples rather than on all of them.  Unless its
+	// AccumulateFitness is set, the PopulationTrainer rescores survivors
+	// every generation, so they are compared with their offspring on the
+	// same batch.  A cortex which reaches the fitness threshold on a batch
+	// only solves the task if it also does on all the examples in use,
+	// see SampledScape.
+	MiniBatchSize int
+
+	// If set, only the easiest examples are used at first, and harder
+	// ones are added as the best fitness of the population improves.
+	// Mini-batches are drawn from the examples in use.
+	Curriculum *Curriculum
+
+	// Indexes of the examples of the current curriculum stage, or nil
+	// to use all of them
+	pool []int
+
+	// Indexes of the examples used in the current generation, which
+	// are a mini-batch of the pool, or the pool itself
+	active []int
+
+	compiledCortexes compiledCortexCache
+}
+
+// Introduces examples from easiest to hardest in stages.  The fitness
+// of each stage is only comparable within the stage, so a
+// PopulationTrainer's FitnessThreshold should not be reachable before
+// the last one.
+type Curriculum struct {
+
+	// Difficulty of each example, parallel to the examples.  Lower is easier.
+	Difficulty []float64
+
+	// Fraction of the examples, easiest first, used in each stage.
+	// The last stage should normally use all of them.
+	StageFractions []float64
+
+	// Best fitness of a generation which moves training from each stage
+	// to the next, so there is one less of these than of StageFractions
+	FitnessThresholds []float64
+
+	stage int
+}
+
+var (
+	_ GenerationalScape = (*TrainingSampleScape)(nil)
+	_ SampledScape      = (*TrainingSampleScape)(nil)
+)
+
+func NewTrainingSampleScape(examples []*ng.TrainingSample) *TrainingSampleScape {
+	return &TrainingSampleScape{examples: examples}
+}
+
+func (scape *TrainingSampleScape) Examples() []*ng.TrainingSample {
+	return scape.examples
+}
+
+// Choose the examples for the generation, advancing the curriculum if
+// the previous generation's best fitness crossed its threshold
+func (scape *TrainingSampleScape) StartGeneration(generation int, bestFitness float64) {
+
+	if scape.Curriculum == nil && scape.MiniBatchSize <= 0 {
+		return
+	}
+
+	pool := make([]int, len(scape.examples))
+	for i := range pool {
+		pool[i] = i
+	}
+	if scape.Curriculum != nil {
+		pool = scape.Curriculum.examples(generation, bestFitness, len(scape.examples))
+	}
+
+	scape.pool = pool
+	scape.active = pool
+
+	if scape.MiniBatchSize > 0 && scape.MiniBatchSize < len(pool) {
+		batch := make([]int, scape.MiniBatchSize)
+		for i, j := range rand.Perm(len(pool))[:scape.MiniBatchSize] {
+			batch[i] = pool[j]
+		}
+		scape.active = batch
+	}
+
+}
+
+// Examples used in the current generation
+func (scape *TrainingSampleScape) ActiveExamples() []*ng.TrainingSample {
+	examples, _ := scape.activeExamples()
+	return examples
+}
+
+func (scape *TrainingSampleScape) Fitness(cortex *ng.Cortex) float64 {
+	return scape.fitness(cortex, scape.active)
+}
+
+// Whether the current generation is scored on a mini-batch
+func (scape *TrainingSampleScape) Sampled() bool {
+	return len(scape.active) < len(scape.pool)
 }
 
-func (scape TrainingSampleScape) Fitness(cortex *ng.Cortex) float64 {
-	return cortex.Fitness(scape.examples)
+// Fitness on all the examples of the current curriculum stage, rather
+// than on the mini-batch of the generation
+func (scape *TrainingSampleScape) FullFitness(cortex *ng.Cortex) float64 {
+	return scape.fitness(cortex, scape.pool)
 }
 
-func (scape TrainingSampleScape) FitnessAgainst(cortex *ng.Cortex, opponentCortex *ng.Cortex) (fitness float64) {
+func (scape *TrainingSampleScape) fitness(cortex *ng.Cortex, indexes []int) float64 {
+	if scape.LossFunction != nil || scape.SampleWeights != nil {
+		return FitnessFromLoss(scape.meanLoss(cortex, indexes))
+	}
+	examples, _ := scape.examplesAt(indexes)
+	if scape.UseCompiledCortex {
+		if compiled, err := scape.compiledCortexes.compile(cortex); err == nil {
+			return boundFitness(compiled.Fitness(examples))
+		}
+	}
+	return boundFitness(cortex.Fitness(examples))
+}
+
+// Weighted mean loss of the cortex over the examples
+func (scape *TrainingSampleScape) MeanLoss(cortex *ng.Cortex) float64 {
+	return scape.meanLoss(cortex, scape.active)
+}
+
+func (scape *TrainingSampleScape) meanLoss(cortex *ng.Cortex, indexes []int) float64 {
+	lossFunction := scape.LossFunction
+	if lossFunction == nil {
+		lossFunction = LossSumOfSquares
+	}
+	examples, weights := scape.examplesAt(indexes)
+	outputs := scape.outputs(cortex, examples)
+	return weightedMeanLoss(lossFunction, examples, outputs, weights)
+}
+
+func (scape *TrainingSampleScape) activeExamples() (examples []*ng.TrainingSample, weights []float64) {
+	return scape.examplesAt(scape.active)
+}
+
+// The examples with the given indexes and their weights, or all of them
+// if indexes is nil
+func (scape *TrainingSampleScape) examplesAt(indexes []int) (examples []*ng.TrainingSample, weights []float64) {
+
+	if indexes == nil {
+		return scape.examples, scape.SampleWeights
+	}
+
+	examples = make([]*ng.TrainingSample, len(indexes))
+	for i, index := range indexes {
+		examples[i] = scape.examples[index]
+	}
+	if scape.SampleWeights != nil {
+		weights = make([]float64, len(indexes))
+		for i, index := range indexes {
+			weights[i] = scape.SampleWeights[index]
+		}
+	}
+	return
+
+}
+
+func (scape *TrainingSampleScape) outputs(cortex *ng.Cortex, examples []*ng.TrainingSample) [][][]float64 {
+	if scape.UseCompiledCortex {
+		if compiled, err := scape.compiledCortexes.compile(cortex); err == nil {
+			outputs := make([][][]float64, len(examples))
+			for i, example := range examples {
+				outputs[i] = compiled.Compute(example.SampleInputs)
+			}
+			return outputs
+		}
+	}
+	return CortexOutputs(cortex, examples)
+}
+
+func (scape *TrainingSampleScape) FitnessAgainst(cortex *ng.Cortex, opponentCortex *ng.Cortex) (fitness float64) {
 	// return cortex.Fitness(scape.examples) - opponentCortex.Fitness(scape.examples)
 	logg.LogPanic("Cannot calculate fitness against another cortex")
 	return 0.0
 }
+
+// The current stage, starting from 0
+func (curriculum *Curriculum) Stage() int {
+	return curriculum.stage
+}
+
+// Advance through the stages whose thresholds were crossed and return
+// the indexes of the examples used in the current one, easiest first
+func (curriculum *Curriculum) examples(generation int, bestFitness float64, numExamples int) []int {
+
+	if len(curriculum.Difficulty) != numExamples {
+		logg.LogPanic("Got %d difficulties for %d examples", len(curriculum.Difficulty), numExamples)
+	}
+	if len(curriculum.StageFractions) == 0 || len(curriculum.FitnessThresholds) != len(curriculum.StageFractions)-1 {
+		logg.LogPanic("Need one less fitness threshold than the %d stages", len(curriculum.StageFractions))
+	}
+
+	for curriculum.stage < len(curriculum.FitnessThresholds) && bestFitness >= curriculum.FitnessThresholds[curriculum.stage] {
+		curriculum.stage += 1
+		logg.LogTo("NEURVOLVE", "Generation %d: best fitness %v, moving to curriculum stage %d", generation, bestFitness, curriculum.stage)
+	}
+
+	byDifficulty := make([]int, numExamples)
+	for i := range byDifficulty {
+		byDifficulty[i] = i
+	}
+	sort.Stable(indexesByDifficulty{byDifficulty, curriculum.Difficulty})
+
+	numUsed := int(curriculum.StageFractions[curriculum.stage]*float64(numExamples) + 0.5)
+	if numUsed < 1 {
+		numUsed = 1
+	}
+	if numUsed > numExamples {
+		numUsed = numExamples
+	}
+	return byDifficulty[:numUsed]
+
+}
+
+type indexesByDifficulty struct {
+	indexes    []int
+	difficulty []float64
+}
+
+func (s indexesByDifficulty) Len() int {
+	return len(s.indexes)
+}
+
+func (s indexesByDifficulty) Swap(i, j int) {
+	s.indexes[i], s.indexes[j] = s.indexes[j], s.indexes[i]
+}
+
+func (s indexesByDifficulty) Less(i, j int) bool {
+	return s.difficulty[s.indexes[i]] < s.difficulty[s.indexes[j]]
+}
@@ -0,0 +1,158 @@
+package neurvolve
+
+import (
+	"github.com/couchbaselabs/go.assert"
+	ng "github.com/maxxk/neurgo"
+	"testing"
+)
+
+// Examples whose input is their index and whose expected output is 0
+func indexedExamples(numExamples int) []*ng.TrainingSample {
+	examples := make([]*ng.TrainingSample, numExamples)
+	for i := range examples {
+		examples[i] = &ng.TrainingSample{
+			SampleInputs:    [][]float64{{float64(i)}},
+			ExpectedOutputs: [][]float64{{0}},
+		}
+	}
+	return examples
+}
+
+func TestTrainingSampleScapeMiniBatch(t *testing.T) {
+
+	examples := indexedExamples(10)
+	scape := NewTrainingSampleScape(examples)
+	scape.MiniBatchSize = 4
+
+	assert.Equals(t, len(scape.ActiveExamples()), 10)
+
+	scape.StartGeneration(0, 0)
+	batch := scape.ActiveExamples()
+	assert.Equals(t, len(batch), 4)
+
+	// the batch is the same for every evaluation in the generation
+	for i, example := range scape.ActiveExamples() {
+		assert.Equals(t, example, batch[i])
+	}
+
+	seen := make(map[*ng.TrainingSample]bool)
+	for _, example := range batch {
+		assert.False(t, seen[example])
+		seen[example] = true
+	}
+
+	// sample weights follow their examples
+	weights := make([]float64, len(examples))
+	for i := range weights {
+		weights[i] = float64(i)
+	}
+	scape.SampleWeights = weights
+	batch, batchWeights := scape.activeExamples()
+	for i, example := range batch {
+		assert.Equals(t, batchWeights[i], example.SampleInputs[0][0])
+	}
+
+}
+
+func TestTrainingSampleScapeCurriculum(t *testing.T) {
+
+	// the single neuron cortex adds 1 to its input, so the absolute
+	// error of each example is its index + 1
+	examples := indexedExamples(4)
+	scape := NewTrainingSampleScape(examples)
+	scape.UseCompiledCortex = true
+	scape.LossFunction = LossMeanAbsoluteError
+	scape.Curriculum = &Curriculum{
+		Difficulty:        []float64{3, 0, 2, 1},
+		StageFractions:    []float64{0.5, 1},
+		FitnessThresholds: []float64{10},
+	}
+	cortex := SingleNeuronCortex("cortex")
+
+	scape.StartGeneration(0, 0)
+	assert.Equals(t, scape.Curriculum.Stage(), 0)
+	active := scape.ActiveExamples()
+	assert.Equals(t, len(active), 2)
+	assert.Equals(t, active[0], examples[1])
+	assert.Equals(t, active[1], examples[3])
+	assert.Equals(t, scape.MeanLoss(cortex), 3.0)
+
+	scape.StartGeneration(1, 9)
+	assert.Equals(t, scape.Curriculum.Stage(), 0)
+
+	scape.StartGeneration(2, 10)
+	assert.Equals(t, scape.Curriculum.Stage(), 1)
+	assert.Equals(t, len(scape.ActiveExamples()), 4)
+	assert.Equals(t, scape.MeanLoss(cortex), 2.5)
+
+	// never goes back to an earlier stage
+	scape.StartGeneration(3, 0)
+	assert.Equals(t, scape.Curriculum.Stage(), 1)
+
+}
+
+func TestTrainStartsGenerations(t *testing.T) {
+
+	pt := &PopulationTrainer{
+		FitnessThreshold: 1000,
+		MaxGenerations:   3,
+		CortexMutator:    NoOpMutator,
+	}
+
+	population := []*ng.Cortex{SingleNeuronCortex("cortex1"), SingleNeuronCortex("cortex2")}
+	scape := &GenerationRecordingScape{ConstantScape: ConstantScape{1}}
+	pt.Train(population, scape, NewNullRecorder())
+
+	assert.Equals(t, len(scape.generations), 3)
+	for i, generation := range scape.generations {
+		assert.Equals(t, generation, i)
+	}
+	assert.True(t, scape.bestFitnesses[0] < 0)
+	assert.Equals(t, scape.bestFitnesses[1], 1.0)
+	assert.Equals(t, scape.bestFitnesses[2], 1.0)
+
+}
+
+type GenerationRecordingScape struct {
+	ConstantScape
+	generations   []int
+	bestFitnesses []float64
+}
+
+func (scape *GenerationRecordingScape) StartGeneration(generation int, bestFitness float64) {
+	scape.generations = append(scape.generations, generation)
+	scape.bestFitnesses = append(scape.bestFitnesses, bestFitness)
+}
+
+func TestTrainingSampleScapeSolvedOnAllExamples(t *testing.T) {
+
+	// the single neuron cortex adds 1 to its input, so its fitness is 1
+	// over the index + 1 of the example in a batch, and 0.4 on all four
+	scape := NewTrainingSampleScape(indexedExamples(4))
+	scape.LossFunction = LossMeanAbsoluteError
+	scape.MiniBatchSize = 1
+	cortex := SingleNeuronCortex("cortex")
+
+	assert.False(t, scape.Sampled())
+	scape.StartGeneration(0, 0)
+	assert.True(t, scape.Sampled())
+	assert.Equals(t, scape.FullFitness(cortex), 0.4)
+
+	pt := &PopulationTrainer{FitnessThreshold: 0.45}
+	population := []EvaluatedCortex{{Cortex: cortex, Fitness: 1}}
+	assert.False(t, pt.solve(population, scape))
+
+	pt.FitnessThreshold = 0.2
+	assert.True(t, pt.solve(population, WeightedSumScape{Scapes: []Scape{scape}}))
+	assert.Equals(t, population[0].Fitness, 0.4)
+
+	// lucky batches don't end training
+	pt = &PopulationTrainer{
+		FitnessThreshold: 0.45,
+		MaxGenerations:   20,
+		CortexMutator:    NoOpMutator,
+	}
+	_, succeeded := pt.Train([]*ng.Cortex{SingleNeuronCortex("cortex1"), SingleNeuronCortex("cortex2")}, scape, NewNullRecorder())
+	assert.False(t, succeeded)
+
+}